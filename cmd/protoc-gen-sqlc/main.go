@@ -5,9 +5,11 @@
 package main
 
 import (
-	"flag"
+	"log/slog"
+	"os"
 
 	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/pluginpb"
 
 	"github.com/pablojimpas/protoc-gen-sqlc/internal/converter"
@@ -15,27 +17,44 @@ import (
 )
 
 func main() {
+	var opts template.Options
+
 	protogen.Options{
-		ParamFunc: flag.CommandLine.Set,
+		ParamFunc: opts.Set,
 	}.Run(
 		func(p *protogen.Plugin) error {
-			p.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
-			tmpl := template.New()
-			opts := template.Options{}
-			sb := converter.NewSchemaBuilder()
+			slog.SetDefault(slog.New(slog.NewTextHandler(
+				os.Stderr,
+				&slog.HandlerOptions{Level: opts.LogLevel},
+			)))
+
+			p.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL |
+				pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS)
+			p.SupportedEditionsMinimum = descriptorpb.Edition_EDITION_PROTO2
+			p.SupportedEditionsMaximum = descriptorpb.Edition_EDITION_2023
+
+			tmpl, err := template.Load(opts)
+			if err != nil {
+				return err
+			}
+
+			sb := converter.NewSchemaBuilder(opts)
 
 			if err := sb.Build(p); err != nil {
 				return err
 			}
 
-			if err := converter.GenerateSchema(p, sb.Schema, tmpl, opts); err != nil {
+			if err := converter.GenerateSchema(p, sb.Schema, sb.Sources, tmpl, opts); err != nil {
 				return err
 			}
 
 			if err := converter.GenerateQueries(
 				p,
 				sb.Schema,
-				sb.FilesByMessage,
+				sb.MessagesByFile,
+				sb.QueryNames,
+				sb.TablesByMessage,
+				sb.ChildrenByMessage,
 				tmpl,
 				opts,
 			); err != nil {
@@ -1,17 +1,17 @@
 -- Code generated by protoc-gen-sqlc. DO NOT EDIT.
 -- source:
---    examples/library/v1/author.proto
---    
+--   examples/library/v1/author.proto
+
 -- name: GetAuthor :one
-SELECT * FROM Author
+SELECT * FROM "Author"
 WHERE author_id = $1 LIMIT 1;
 
 -- name: ListAuthor :many
-SELECT * FROM Author
+SELECT * FROM "Author"
 ORDER BY author_id;
 
 -- name: CreateAuthor :one
-INSERT INTO Author (
+INSERT INTO "Author" (
   author_id, name, biography
 ) VALUES (
   $1, $2, $3
@@ -19,12 +19,12 @@ INSERT INTO Author (
 RETURNING *;
 
 -- name: UpdateAuthor :one
-UPDATE Author SET
-  name = $2, 
+UPDATE "Author" SET
+  name = $2,
   biography = $3
 WHERE author_id = $1
 RETURNING *;
 
 -- name: DeleteAuthor :exec
-DELETE FROM Author
+DELETE FROM "Author"
 WHERE author_id = $1;
@@ -1,17 +1,17 @@
 -- Code generated by protoc-gen-sqlc. DO NOT EDIT.
 -- source:
---    examples/library/v1/book.proto
---    
+--   examples/library/v1/book.proto
+
 -- name: GetBook :one
-SELECT * FROM Book
+SELECT * FROM "Book"
 WHERE book_id = $1 LIMIT 1;
 
 -- name: ListBook :many
-SELECT * FROM Book
+SELECT * FROM "Book"
 ORDER BY book_id;
 
 -- name: CreateBook :one
-INSERT INTO Book (
+INSERT INTO "Book" (
   book_id, author_id, isbn, book_type, title, year, available_time, tags, published, price
 ) VALUES (
   $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
@@ -19,19 +19,19 @@ INSERT INTO Book (
 RETURNING *;
 
 -- name: UpdateBook :one
-UPDATE Book SET
-  author_id = $2, 
-  isbn = $3, 
-  book_type = $4, 
-  title = $5, 
-  year = $6, 
-  available_time = $7, 
-  tags = $8, 
-  published = $9, 
+UPDATE "Book" SET
+  author_id = $2,
+  isbn = $3,
+  book_type = $4,
+  title = $5,
+  year = $6,
+  available_time = $7,
+  tags = $8,
+  published = $9,
   price = $10
 WHERE book_id = $1
 RETURNING *;
 
 -- name: DeleteBook :exec
-DELETE FROM Book
+DELETE FROM "Book"
 WHERE book_id = $1;
@@ -10,7 +10,7 @@ import (
 )
 
 const createAuthor = `-- name: CreateAuthor :one
-INSERT INTO Author (
+INSERT INTO "Author" (
   author_id, name, biography
 ) VALUES (
   $1, $2, $3
@@ -32,7 +32,7 @@ func (q *Queries) CreateAuthor(ctx context.Context, arg CreateAuthorParams) (Aut
 }
 
 const deleteAuthor = `-- name: DeleteAuthor :exec
-DELETE FROM Author
+DELETE FROM "Author"
 WHERE author_id = $1
 `
 
@@ -42,7 +42,7 @@ func (q *Queries) DeleteAuthor(ctx context.Context, authorID int32) error {
 }
 
 const getAuthor = `-- name: GetAuthor :one
-SELECT author_id, name, biography FROM Author
+SELECT author_id, name, biography FROM "Author"
 WHERE author_id = $1 LIMIT 1
 `
 
@@ -58,7 +58,7 @@ func (q *Queries) GetAuthor(ctx context.Context, authorID int32) (Author, error)
 }
 
 const listAuthor = `-- name: ListAuthor :many
-SELECT author_id, name, biography FROM Author
+SELECT author_id, name, biography FROM "Author"
 ORDER BY author_id
 `
 
@@ -83,8 +83,8 @@ func (q *Queries) ListAuthor(ctx context.Context) ([]Author, error) {
 }
 
 const updateAuthor = `-- name: UpdateAuthor :one
-UPDATE Author SET
-  name = $2, 
+UPDATE "Author" SET
+  name = $2,
   biography = $3
 WHERE author_id = $1
 RETURNING author_id, name, biography
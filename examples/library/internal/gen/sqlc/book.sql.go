@@ -12,7 +12,7 @@ import (
 )
 
 const createBook = `-- name: CreateBook :one
-INSERT INTO Book (
+INSERT INTO "Book" (
   book_id, author_id, isbn, book_type, title, year, available_time, tags, published, price
 ) VALUES (
   $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
@@ -24,13 +24,13 @@ type CreateBookParams struct {
 	BookID        int32
 	AuthorID      int32
 	Isbn          string
-	BookType      Booktype
+	BookType      BookType
 	Title         string
 	Year          int32
 	AvailableTime pgtype.Timestamptz
 	Tags          []string
 	Published     pgtype.Bool
-	Price         pgtype.Float8
+	Price         pgtype.Float4
 }
 
 func (q *Queries) CreateBook(ctx context.Context, arg CreateBookParams) (Book, error) {
@@ -63,7 +63,7 @@ func (q *Queries) CreateBook(ctx context.Context, arg CreateBookParams) (Book, e
 }
 
 const deleteBook = `-- name: DeleteBook :exec
-DELETE FROM Book
+DELETE FROM "Book"
 WHERE book_id = $1
 `
 
@@ -73,7 +73,7 @@ func (q *Queries) DeleteBook(ctx context.Context, bookID int32) error {
 }
 
 const getBook = `-- name: GetBook :one
-SELECT book_id, author_id, isbn, book_type, title, year, available_time, tags, published, price FROM Book
+SELECT book_id, author_id, isbn, book_type, title, year, available_time, tags, published, price FROM "Book"
 WHERE book_id = $1 LIMIT 1
 `
 
@@ -100,7 +100,7 @@ func (q *Queries) GetBook(ctx context.Context, bookID int32) (Book, error) {
 }
 
 const listBook = `-- name: ListBook :many
-SELECT book_id, author_id, isbn, book_type, title, year, available_time, tags, published, price FROM Book
+SELECT book_id, author_id, isbn, book_type, title, year, available_time, tags, published, price FROM "Book"
 ORDER BY book_id
 `
 
@@ -136,15 +136,15 @@ func (q *Queries) ListBook(ctx context.Context) ([]Book, error) {
 }
 
 const updateBook = `-- name: UpdateBook :one
-UPDATE Book SET
-  author_id = $2, 
-  isbn = $3, 
-  book_type = $4, 
-  title = $5, 
-  year = $6, 
-  available_time = $7, 
-  tags = $8, 
-  published = $9, 
+UPDATE "Book" SET
+  author_id = $2,
+  isbn = $3,
+  book_type = $4,
+  title = $5,
+  year = $6,
+  available_time = $7,
+  tags = $8,
+  published = $9,
   price = $10
 WHERE book_id = $1
 RETURNING book_id, author_id, isbn, book_type, title, year, available_time, tags, published, price
@@ -154,13 +154,13 @@ type UpdateBookParams struct {
 	BookID        int32
 	AuthorID      int32
 	Isbn          string
-	BookType      Booktype
+	BookType      BookType
 	Title         string
 	Year          int32
 	AvailableTime pgtype.Timestamptz
 	Tags          []string
 	Published     pgtype.Bool
-	Price         pgtype.Float8
+	Price         pgtype.Float4
 }
 
 func (q *Queries) UpdateBook(ctx context.Context, arg UpdateBookParams) (Book, error) {
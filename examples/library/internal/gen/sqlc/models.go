@@ -11,47 +11,47 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-type Booktype string
+type BookType string
 
 const (
-	BooktypeBOOKTYPEUNSPECIFIED Booktype = "BOOK_TYPE_UNSPECIFIED"
-	BooktypeBOOKTYPEFICTION     Booktype = "BOOK_TYPE_FICTION"
-	BooktypeBOOKTYPENONFICTION  Booktype = "BOOK_TYPE_NONFICTION"
+	BookTypeBOOKTYPEUNSPECIFIED BookType = "BOOK_TYPE_UNSPECIFIED"
+	BookTypeBOOKTYPEFICTION     BookType = "BOOK_TYPE_FICTION"
+	BookTypeBOOKTYPENONFICTION  BookType = "BOOK_TYPE_NONFICTION"
 )
 
-func (e *Booktype) Scan(src interface{}) error {
+func (e *BookType) Scan(src interface{}) error {
 	switch s := src.(type) {
 	case []byte:
-		*e = Booktype(s)
+		*e = BookType(s)
 	case string:
-		*e = Booktype(s)
+		*e = BookType(s)
 	default:
-		return fmt.Errorf("unsupported scan type for Booktype: %T", src)
+		return fmt.Errorf("unsupported scan type for BookType: %T", src)
 	}
 	return nil
 }
 
-type NullBooktype struct {
-	Booktype Booktype
-	Valid    bool // Valid is true if Booktype is not NULL
+type NullBookType struct {
+	BookType BookType
+	Valid    bool // Valid is true if BookType is not NULL
 }
 
 // Scan implements the Scanner interface.
-func (ns *NullBooktype) Scan(value interface{}) error {
+func (ns *NullBookType) Scan(value interface{}) error {
 	if value == nil {
-		ns.Booktype, ns.Valid = "", false
+		ns.BookType, ns.Valid = "", false
 		return nil
 	}
 	ns.Valid = true
-	return ns.Booktype.Scan(value)
+	return ns.BookType.Scan(value)
 }
 
 // Value implements the driver Valuer interface.
-func (ns NullBooktype) Value() (driver.Value, error) {
+func (ns NullBookType) Value() (driver.Value, error) {
 	if !ns.Valid {
 		return nil, nil
 	}
-	return string(ns.Booktype), nil
+	return string(ns.BookType), nil
 }
 
 type Author struct {
@@ -64,11 +64,11 @@ type Book struct {
 	BookID        int32
 	AuthorID      int32
 	Isbn          string
-	BookType      Booktype
+	BookType      BookType
 	Title         string
 	Year          int32
 	AvailableTime pgtype.Timestamptz
 	Tags          []string
 	Published     pgtype.Bool
-	Price         pgtype.Float8
+	Price         pgtype.Float4
 }
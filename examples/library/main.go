@@ -93,13 +93,13 @@ func run() error {
 		BookID:        1,
 		AuthorID:      fetchedAuthor.AuthorID,
 		Isbn:          "ABC123",
-		BookType:      example.BooktypeBOOKTYPENONFICTION,
+		BookType:      example.BookTypeBOOKTYPENONFICTION,
 		Title:         "The C Programming Language",
 		Year:          1983,
 		AvailableTime: pgtype.Timestamptz{Time: time.Now(), Valid: true},
 		Tags:          []string{"programming", "c"},
 		Published:     pgtype.Bool{Bool: true, Valid: true},
-		Price:         pgtype.Float8{Float64: 19.99, Valid: true},
+		Price:         pgtype.Float4{Float32: 19.99, Valid: true},
 	})
 
 	log.Println("Inserted book")
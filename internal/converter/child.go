@@ -0,0 +1,316 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/core"
+	sqlcpb "github.com/pablojimpas/protoc-gen-sqlc/internal/gen/sqlc"
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/sqlc/template"
+)
+
+const (
+	// Name of the key column of a map child table.
+	mapKeyColumn = "key"
+	// Name of the value column of a map child table.
+	mapValueColumn = "value"
+	// Name of the column holding the position of a repeated field element.
+	listOrdinalColumn = "ordinal"
+)
+
+// ChildTable describes a table generated for a field of a message rather
+// than for a message, such as a map stored as one row per entry or a repeated
+// message stored as one row per element.
+type ChildTable struct {
+	// GoName is the name used for the child table queries.
+	GoName string
+	// Table is the name of the child table.
+	Table string
+	// ParentKey lists the columns referencing the parent primary key.
+	ParentKey []string
+	// Key is the column identifying a row among those of the same parent.
+	Key string
+}
+
+// fieldConstraints returns the sqlc extension of a field, or nil if the field
+// has none.
+func fieldConstraints(field *protogen.Field) *sqlcpb.FieldConstraints {
+	opts := field.Desc.Options()
+	if !proto.HasExtension(opts, sqlcpb.E_Field) {
+		return nil
+	}
+
+	ext, ok := proto.GetExtension(opts, sqlcpb.E_Field).(*sqlcpb.FieldConstraints)
+	if !ok {
+		slog.Warn(
+			"invalid extension type for field",
+			slog.String("field", string(field.Desc.Name())),
+		)
+
+		return nil
+	}
+
+	return ext
+}
+
+// storedInChildTable reports whether a field is stored in a child table
+// instead of a column of the message table.
+func storedInChildTable(field *protogen.Field) bool {
+	switch {
+	case field.Desc.IsMap():
+		return fieldConstraints(field).GetMapStorage() == sqlcpb.MapStorage_MAP_STORAGE_TABLE
+	case field.Desc.IsList() && field.Message != nil:
+		return fieldConstraints(field).GetChildTable()
+	default:
+		return false
+	}
+}
+
+// buildChildTable creates the child table storing a field of a message.
+func (sb *SchemaBuilder) buildChildTable(
+	parentMessage *protogen.Message,
+	field *protogen.Field,
+	parent *core.Table,
+) (core.Table, ChildTable, error) {
+	if field.Desc.IsMap() {
+		return buildMapTable(parentMessage, field, parent, sb.Options)
+	}
+
+	return sb.buildListTable(parentMessage, field, parent)
+}
+
+// mapMapType converts a map field to a SQL column type according to its
+// storage option.
+func mapMapType(field *protogen.Field) (core.ColumnType, error) {
+	switch storage := fieldConstraints(field).GetMapStorage(); storage {
+	case sqlcpb.MapStorage_MAP_STORAGE_UNSPECIFIED, sqlcpb.MapStorage_MAP_STORAGE_JSONB:
+		return core.JSONBType, nil
+	case sqlcpb.MapStorage_MAP_STORAGE_HSTORE:
+		if field.Desc.MapKey().Kind() != protoreflect.StringKind ||
+			field.Desc.MapValue().Kind() != protoreflect.StringKind {
+			return "", errors.New("hstore storage requires map<string, string>")
+		}
+
+		return core.HStoreType, nil
+	case sqlcpb.MapStorage_MAP_STORAGE_TABLE:
+		return "", errors.New("map stored in a child table has no column")
+	default:
+		return "", fmt.Errorf("unknown map storage %v", storage)
+	}
+}
+
+// buildMapTable creates the child table storing the entries of a map field,
+// with one row per entry keyed by the parent primary key and the map key.
+func buildMapTable(
+	parentMessage *protogen.Message,
+	field *protogen.Field,
+	parent *core.Table,
+	opts template.Options,
+) (core.Table, ChildTable, error) {
+	// The key and value of a map are the fields of its synthetic entry message
+	keyType, err := mapElementType(field.Message.Fields[0], opts)
+	if err != nil {
+		return core.Table{}, ChildTable{}, fmt.Errorf("mapping key type: %w", err)
+	}
+
+	keyType = boundedType(keyType, fieldRules(field).GetMap().GetKeys().GetString(), opts)
+
+	valueType, err := mapDataType(field.Message.Fields[1], opts)
+	if err != nil {
+		return core.Table{}, ChildTable{}, fmt.Errorf("mapping value type: %w", err)
+	}
+
+	name := identifierName(parent.Name, columnName(string(field.Desc.Name()), opts))
+
+	table, err := newChildTable(name, parentMessage, parent)
+	if err != nil {
+		return core.Table{}, ChildTable{}, err
+	}
+
+	table.Columns = append(table.Columns,
+		core.Column{Name: mapKeyColumn, Type: keyType, NotNull: true},
+		core.Column{Name: mapValueColumn, Type: valueType},
+	)
+	table.Constraints[0].Columns = append(table.Constraints[0].Columns, mapKeyColumn)
+
+	child := ChildTable{
+		GoName:    queryName(parentMessage) + field.GoName,
+		Table:     name,
+		ParentKey: parentKeyColumns(parentMessage, parent),
+		Key:       mapKeyColumn,
+	}
+
+	return table, child, nil
+}
+
+// buildListTable creates the child table storing the elements of a repeated
+// message field, with one row per element keyed by the parent primary key and
+// the position of the element.
+func (sb *SchemaBuilder) buildListTable(
+	parentMessage *protogen.Message,
+	field *protogen.Field,
+	parent *core.Table,
+) (core.Table, ChildTable, error) {
+	opts := sb.Options
+
+	name := identifierName(parent.Name, columnName(string(field.Desc.Name()), opts))
+
+	table, err := newChildTable(name, parentMessage, parent)
+	if err != nil {
+		return core.Table{}, ChildTable{}, err
+	}
+
+	table.Columns = append(table.Columns,
+		core.Column{Name: listOrdinalColumn, Type: core.IntegerType, NotNull: true},
+	)
+	table.Constraints[0].Columns = append(table.Constraints[0].Columns, listOrdinalColumn)
+
+	// Elements have no key of their own for child tables to refer to
+	if nested := slices.IndexFunc(field.Message.Fields, storedInChildTable); nested >= 0 {
+		return core.Table{}, ChildTable{}, fmt.Errorf(
+			"%w: element field %s", ErrNestedChildTable, field.Message.Fields[nested].Desc.Name(),
+		)
+	}
+
+	fields, err := columnFields(field.Message, opts)
+	if err != nil {
+		return core.Table{}, ChildTable{}, err
+	}
+
+	columns, err := buildColumns(fields, opts)
+	if err != nil {
+		return core.Table{}, ChildTable{}, fmt.Errorf("building columns: %w", err)
+	}
+
+	for _, column := range columns {
+		if table.ColumnByName(column.Name) != nil {
+			return core.Table{}, ChildTable{}, fmt.Errorf(
+				"column %s of %s clashes with a column added by the child table",
+				column.Name, field.Message.Desc.Name(),
+			)
+		}
+
+		table.Columns = append(table.Columns, column)
+	}
+
+	constraints, err := sb.buildConstraints(field.Message, fields)
+	if err != nil {
+		return core.Table{}, ChildTable{}, fmt.Errorf("building constraints: %w", err)
+	}
+
+	for _, constraint := range constraints {
+		// The child table is keyed by its parent, not by the element key
+		if constraint.Type != core.PrimaryKeyConstraint {
+			table.Constraints = append(table.Constraints, constraint)
+		}
+	}
+
+	checks, err := buildChecks(fields, name, opts)
+	if err != nil {
+		return core.Table{}, ChildTable{}, fmt.Errorf("building checks: %w", err)
+	}
+
+	table.Constraints = append(table.Constraints, checks...)
+	table.Constraints = append(table.Constraints, enumReferences(fields, opts)...)
+
+	table.Indexes, err = buildIndexes(field.Message, fields, name, opts)
+	if err != nil {
+		return core.Table{}, ChildTable{}, fmt.Errorf("building indexes: %w", err)
+	}
+
+	if err := checkConstraintColumns(&table); err != nil {
+		return core.Table{}, ChildTable{}, fmt.Errorf("checking constraints: %w", err)
+	}
+
+	child := ChildTable{
+		GoName:    queryName(parentMessage) + field.GoName,
+		Table:     name,
+		ParentKey: parentKeyColumns(parentMessage, parent),
+		Key:       listOrdinalColumn,
+	}
+
+	return table, child, nil
+}
+
+// newChildTable creates a table holding a copy of the parent primary key
+// columns, which are both a foreign key to the parent and the leading
+// columns of the child primary key.
+func newChildTable(
+	name string,
+	parentMessage *protogen.Message,
+	parent *core.Table,
+) (core.Table, error) {
+	parentKey := parent.PrimaryKey()
+	columns := parentKeyColumns(parentMessage, parent)
+
+	table := core.Table{Name: name}
+
+	for i, key := range parentKey {
+		parentColumn := parent.ColumnByName(key)
+		if parentColumn == nil {
+			return core.Table{}, fmt.Errorf("parent table %s has no primary key", parent.Name)
+		}
+
+		column := core.Column{Name: columns[i], Type: parentColumn.Type, NotNull: true}
+		switch column.Type {
+		case core.SerialType:
+			column.Type = core.IntegerType
+		case core.BigSerialType:
+			column.Type = core.BigIntType
+		}
+
+		table.Columns = append(table.Columns, column)
+	}
+
+	table.Constraints = []core.Constraint{
+		{
+			Type:    core.PrimaryKeyConstraint,
+			Columns: slices.Clone(columns),
+		},
+		{
+			Type:    core.ForeignKeyConstraint,
+			Columns: columns,
+			References: &core.Reference{
+				Table:    parent.Name,
+				Columns:  parentKey,
+				OnDelete: core.ForeignKeyActionCascade,
+				OnUpdate: core.ForeignKeyActionCascade,
+			},
+		},
+	}
+
+	return table, nil
+}
+
+// parentKeyColumns returns the names of the child table columns copying the
+// parent primary key, prefixed with the parent message name in snake_case so
+// that they do not clash with the element fields: the id of an Order becomes
+// order_id. Keys that already start with the prefix, such as order_id, are
+// kept as is.
+func parentKeyColumns(parentMessage *protogen.Message, parent *core.Table) []string {
+	prefix := snakeCase(queryName(parentMessage)) + "_"
+
+	parentKey := parent.PrimaryKey()
+	columns := make([]string, 0, len(parentKey))
+
+	for _, key := range parentKey {
+		if !strings.HasPrefix(key, prefix) {
+			key = identifierName(prefix + key)
+		}
+
+		columns = append(columns, key)
+	}
+
+	return columns
+}
@@ -6,10 +6,16 @@
 package converter
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"log/slog"
+	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
 	"google.golang.org/protobuf/compiler/protogen"
@@ -24,26 +30,59 @@ import (
 const (
 	// Number of parts expected in a "references" string. (table.column).
 	referencesPartCount = 2
+	// Maximum length of a PostgreSQL identifier (NAMEDATALEN - 1).
+	maxIdentifierLength = 63
+	// Full name of the google.type.Money message.
+	moneyFullName = "google.type.Money"
+	// Suffix of the column holding the currency code of a money field.
+	moneyCurrencySuffix = "_currency"
 )
 
 var (
-	ErrNilEnum       = errors.New("nil enum provided")
-	ErrNilMessage    = errors.New("nil message provided")
-	ErrNilOptions    = errors.New("nil options provided")
-	ErrTableNotFound = errors.New("table not found")
+	ErrNilEnum              = errors.New("nil enum provided")
+	ErrNilMessage           = errors.New("nil message provided")
+	ErrNilOptions           = errors.New("nil options provided")
+	ErrTableNotFound        = errors.New("table not found")
+	ErrColumnNotFound       = errors.New("column not found")
+	ErrMultiplePrimaryKeys  = errors.New("multiple primary keys declared")
+	ErrSetNullOnNotNull     = errors.New("SET NULL action on NOT NULL column")
+	ErrUnindexableKey       = errors.New("key on a column the dialect cannot index")
+	ErrDuplicateName        = errors.New("name already used")
+	ErrNestedChildTable     = errors.New("child table field outside of a table message")
+	ErrInvalidAutoIncrement = errors.New("auto_increment on a field that is not an integer key")
 )
 
 // SchemaBuilder transforms protobuf definitions into SQL schema structures.
+// Messages are identified by their full name in the maps it builds.
 type SchemaBuilder struct {
-	Schema         core.Schema
-	FilesByMessage map[string]*protogen.File
+	Schema            core.Schema
+	Sources           []string
+	MessagesByFile    map[string][]string
+	QueryNames        map[string]string
+	TablesByMessage   map[string]string
+	ChildrenByMessage map[string][]ChildTable
+	Options           template.Options
+
+	// messages indexes the messages of every file of the request, to resolve
+	// the messages named by references.
+	messages map[protoreflect.FullName]*protogen.Message
+
+	// queries maps the names of the queries built so far to the message
+	// they were built for, as queries of every file share a namespace.
+	queries map[string]protoreflect.FullName
 }
 
 // NewSchemaBuilder creates a new SchemaBuilder with initialized fields.
-func NewSchemaBuilder() *SchemaBuilder {
+func NewSchemaBuilder(opts template.Options) *SchemaBuilder {
 	return &SchemaBuilder{
-		Schema:         core.Schema{},
-		FilesByMessage: make(map[string]*protogen.File),
+		Schema:            core.Schema{},
+		MessagesByFile:    make(map[string][]string),
+		QueryNames:        make(map[string]string),
+		TablesByMessage:   make(map[string]string),
+		ChildrenByMessage: make(map[string][]ChildTable),
+		Options:           opts,
+		messages:          make(map[protoreflect.FullName]*protogen.Message),
+		queries:           make(map[string]protoreflect.FullName),
 	}
 }
 
@@ -53,44 +92,74 @@ func (sb *SchemaBuilder) Build(p *protogen.Plugin) error {
 		return errors.New("nil plugin provided")
 	}
 
+	for _, f := range p.Files {
+		for _, message := range nestedMessages(f.Messages) {
+			sb.messages[message.Desc.FullName()] = message
+		}
+	}
+
 	for _, name := range p.Request.GetFileToGenerate() {
 		f := p.FilesByPath[name]
 
-		if len(f.Messages) == 0 {
-			slog.Debug("skip generating file because it has no messages", slog.String("name", name))
+		if len(f.Messages) == 0 && len(f.Enums) == 0 {
+			slog.Debug("skip generating file because it has no messages or enums",
+				slog.String("name", name))
 
 			continue
 		}
 
 		slog.Debug("processing file", slog.String("name", name))
 
-		for _, enum := range f.Enums {
-			if err := sb.buildEnum(enum); err != nil {
-				slog.Warn(
-					"failed to build enum",
-					slog.String("name", string(enum.Desc.Name())),
-					slog.String("error", err.Error()),
-				)
+		tables, enumCount := len(sb.Schema.Tables), len(sb.Schema.Enums)
 
-				continue
+		enums := slices.Clone(f.Enums)
+		for _, message := range f.Messages {
+			enums = append(enums, nestedEnums(message)...)
+		}
+
+		for _, enum := range enums {
+			if err := sb.buildEnum(enum); err != nil {
+				return fmt.Errorf("building enum %s: %w", enum.Desc.FullName(), err)
 			}
 		}
 
-		for _, message := range f.Messages {
-			sb.FilesByMessage[string(message.Desc.Name())] = f
+		messages := f.Messages
+		if sb.Options.NestedMessages {
+			messages = nestedMessages(f.Messages)
+		}
 
-			if err := sb.buildMessage(message); err != nil {
-				slog.Warn(
-					"failed to build message",
-					slog.String("name", string(message.Desc.Name())),
-					slog.String("error", err.Error()),
+		for _, message := range messages {
+			if !sb.includeMessage(message) {
+				slog.Debug(
+					"skip generating table for message",
+					slog.String("name", string(message.Desc.FullName())),
 				)
 
 				continue
 			}
+
+			if err := sb.buildMessage(message); err != nil {
+				return fmt.Errorf("building message %s: %w", message.Desc.FullName(), err)
+			}
+
+			sb.MessagesByFile[name] = append(sb.MessagesByFile[name], string(message.Desc.FullName()))
+		}
+
+		if len(sb.Schema.Tables) > tables || len(sb.Schema.Enums) > enumCount {
+			sb.Sources = append(sb.Sources, name)
 		}
 	}
 
+	if err := checkNames(sb.Schema, sb.Options.SQLDialect()); err != nil {
+		return err
+	}
+
+	if err := restrictSchema(&sb.Schema, sb.Options.SQLDialect()); err != nil {
+		return err
+	}
+
+	sortSchema(&sb.Schema)
+
 	return nil
 }
 
@@ -100,14 +169,14 @@ func (sb *SchemaBuilder) buildEnum(protoEnum *protogen.Enum) error {
 		return ErrNilEnum
 	}
 
-	values := make([]string, 0, len(protoEnum.Values))
-	for _, v := range protoEnum.Values {
-		values = append(values, string(v.Desc.Name()))
+	enum, err := sqlEnum(protoEnum, sb.Options)
+	if err != nil {
+		return err
 	}
 
-	enum := core.Enum{
-		Name:   protoEnum.GoIdent.GoName,
-		Values: values,
+	// Enum types and lookup tables share the namespace of the schema
+	if slices.ContainsFunc(sb.Schema.Enums, func(e core.Enum) bool { return e.Name == enum.Name }) {
+		return fmt.Errorf("enum name %s is already used by another enum", enum.Name)
 	}
 
 	sb.Schema.Enums = append(sb.Schema.Enums, enum)
@@ -115,45 +184,249 @@ func (sb *SchemaBuilder) buildEnum(protoEnum *protogen.Enum) error {
 	return nil
 }
 
+// checkNames returns an error if two tables share a name, or a table is named
+// like an enum type or lookup table, such as a nested Book.Format enum and a
+// BookFormat message in snake_case. PostgreSQL creates a row type along with
+// every table, so tables and enum types share a namespace.
+func checkNames(schema core.Schema, dialect core.Dialect) error {
+	names := make(map[string]bool, len(schema.Tables)+len(schema.Enums))
+
+	for _, enum := range schema.Enums {
+		if enum.Storage == core.EnumStorageTable ||
+			(enum.Storage == core.EnumStorageNative && dialect.Supports(core.FeatureEnumTypes)) {
+			names[enum.Name] = true
+		}
+	}
+
+	for _, table := range schema.Tables {
+		if names[table.Name] {
+			return fmt.Errorf(
+				"%w: table %s is named like another table or enum", ErrDuplicateName, table.Name,
+			)
+		}
+
+		names[table.Name] = true
+	}
+
+	return nil
+}
+
 // buildMessage converts a protobuf message to a SQL table.
 func (sb *SchemaBuilder) buildMessage(protoMessage *protogen.Message) error {
 	if protoMessage == nil {
 		return ErrNilMessage
 	}
 
-	name := protoMessage.Desc.Name()
+	name := tableName(protoMessage, sb.Options)
+	fields, err := columnFields(protoMessage, sb.Options)
+	if err != nil {
+		return err
+	}
 
-	columns, err := buildColumns(protoMessage)
+	columns, err := buildColumns(fields, sb.Options)
 	if err != nil {
 		return fmt.Errorf("building columns: %w", err)
 	}
 
-	constraints, err := buildConstraints(protoMessage)
+	constraints, err := sb.buildConstraints(protoMessage, fields)
 	if err != nil {
 		return fmt.Errorf("building constraints: %w", err)
 	}
 
+	checks, err := buildChecks(fields, name, sb.Options)
+	if err != nil {
+		return fmt.Errorf("building checks: %w", err)
+	}
+
+	constraints = append(constraints, checks...)
+	constraints = append(constraints, enumReferences(fields, sb.Options)...)
+
+	indexes, err := buildIndexes(protoMessage, fields, name, sb.Options)
+	if err != nil {
+		return fmt.Errorf("building indexes: %w", err)
+	}
+
 	table := core.Table{
-		Name:        string(name),
+		Name:        name,
 		Columns:     columns,
 		Constraints: constraints,
+		Indexes:     indexes,
+	}
+
+	if err := checkConstraintColumns(&table); err != nil {
+		return fmt.Errorf("checking constraints: %w", err)
+	}
+
+	tables := []core.Table{table}
+
+	var children []ChildTable
+
+	for _, field := range protoMessage.Fields {
+		if !storedInChildTable(field) {
+			continue
+		}
+
+		childTable, child, err := sb.buildChildTable(protoMessage, field, &table)
+		if err != nil {
+			return fmt.Errorf("building child table for %s: %w", field.Desc.Name(), err)
+		}
+
+		tables = append(tables, childTable)
+		children = append(children, child)
+	}
+
+	for _, t := range tables {
+		for _, column := range t.Columns {
+			if column.Type == core.HStoreType &&
+				sb.Options.SQLDialect().Supports(core.FeatureExtensions) &&
+				!slices.Contains(sb.Schema.Extensions, "hstore") {
+				sb.Schema.Extensions = append(sb.Schema.Extensions, "hstore")
+			}
+		}
 	}
 
-	sb.Schema.Tables = append(sb.Schema.Tables, table)
+	// Nested and top-level messages may join to the same query name, such
+	// as Book.Shelf and BookShelf, and so may their child tables
+	fullName := protoMessage.Desc.FullName()
+
+	queries := []string{queryName(protoMessage)}
+	for _, child := range children {
+		queries = append(queries, child.GoName)
+	}
+
+	for _, query := range queries {
+		if other, ok := sb.queries[query]; ok {
+			return fmt.Errorf("%w: queries %s are also built for %s", ErrDuplicateName, query, other)
+		}
+
+		sb.queries[query] = fullName
+	}
+
+	sb.Schema.Tables = append(sb.Schema.Tables, tables...)
+	sb.QueryNames[string(fullName)] = queryName(protoMessage)
+	sb.TablesByMessage[string(fullName)] = name
+	sb.ChildrenByMessage[string(fullName)] = children
 
 	return nil
 }
 
-// buildColumns converts protobuf message fields to SQL columns.
-func buildColumns(protoMessage *protogen.Message) ([]core.Column, error) {
-	if protoMessage == nil {
-		return nil, ErrNilMessage
+// includeMessage reports whether a protobuf message should become a SQL table.
+func (sb *SchemaBuilder) includeMessage(protoMessage *protogen.Message) bool {
+	ext := tableOptions(protoMessage)
+	if ext.GetSkip() {
+		return false
 	}
 
-	columns := make([]core.Column, 0, len(protoMessage.Fields))
+	if sb.Options.OnlyAnnotated {
+		return ext.GetInclude()
+	}
 
-	for _, field := range protoMessage.Fields {
-		columnType, err := mapDataType(field)
+	return true
+}
+
+// isTable reports whether a protobuf message is stored in a SQL table.
+func (sb *SchemaBuilder) isTable(protoMessage *protogen.Message) bool {
+	if protoMessage.Desc.IsMapEntry() || !sb.includeMessage(protoMessage) {
+		return false
+	}
+
+	_, nested := protoMessage.Desc.Parent().(protoreflect.MessageDescriptor)
+
+	return !nested || sb.Options.NestedMessages
+}
+
+// resolveReference returns the SQL name of the table referenced by message name
+// in a (sqlc.field).references option of a field. The message is looked up in
+// the package of the field, and its table is named like the table built for it.
+// A name matching no message is taken as the name of a table defined elsewhere.
+func (sb *SchemaBuilder) resolveReference(field *protogen.Field, name string) (string, error) {
+	fullName := field.Desc.ParentFile().Package().Append(protoreflect.Name(name))
+
+	message, ok := sb.messages[fullName]
+	if !ok {
+		return referencedTable(name, sb.Options), nil
+	}
+
+	if !sb.isTable(message) {
+		return "", fmt.Errorf("%w: message %s is not stored in a table", ErrTableNotFound, name)
+	}
+
+	return tableName(message, sb.Options), nil
+}
+
+// tableOptions returns the sqlc table extension of a message, or nil if the
+// message has none.
+func tableOptions(protoMessage *protogen.Message) *sqlcpb.TableOptions {
+	opts := protoMessage.Desc.Options()
+	if !proto.HasExtension(opts, sqlcpb.E_Table) {
+		return nil
+	}
+
+	ext, ok := proto.GetExtension(opts, sqlcpb.E_Table).(*sqlcpb.TableOptions)
+	if !ok {
+		slog.Warn(
+			"invalid extension type for message",
+			slog.String("message", string(protoMessage.Desc.Name())),
+		)
+
+		return nil
+	}
+
+	return ext
+}
+
+// tableName returns the SQL table name for a message according to the naming
+// strategy, honoring the (sqlc.table).name override. Nested messages are
+// qualified with the names of their parents like nested enums, so
+// Library.Shelf becomes Library_Shelf, or library_shelf in snake_case.
+func tableName(protoMessage *protogen.Message, opts template.Options) string {
+	if name := tableOptions(protoMessage).GetName(); name != "" {
+		return name
+	}
+
+	return pluralTable(qualifiedName(messagePath(protoMessage), opts), opts)
+}
+
+// queryName returns the name used for the queries of a message, which joins
+// the names of nested messages: Library.Shelf becomes LibraryShelf.
+func queryName(protoMessage *protogen.Message) string {
+	return strings.Join(messagePath(protoMessage), "")
+}
+
+// messagePath returns the names of the messages a message is nested in,
+// followed by its own name.
+func messagePath(protoMessage *protogen.Message) []string {
+	name := strings.TrimPrefix(
+		string(protoMessage.Desc.FullName()),
+		string(protoMessage.Desc.ParentFile().Package())+".",
+	)
+
+	return strings.Split(name, ".")
+}
+
+// nestedMessages returns the given messages, each followed by the messages
+// nested in it, recursively. Synthetic map entry messages are left out.
+func nestedMessages(messages []*protogen.Message) []*protogen.Message {
+	var all []*protogen.Message
+
+	for _, message := range messages {
+		if message.Desc.IsMapEntry() {
+			continue
+		}
+
+		all = append(all, message)
+		all = append(all, nestedMessages(message.Messages)...)
+	}
+
+	return all
+}
+
+// buildColumns converts protobuf message fields to SQL columns.
+func buildColumns(fields []columnField, opts template.Options) ([]core.Column, error) {
+	columns := make([]core.Column, 0, len(fields))
+
+	for _, field := range fields {
+		columnType, err := mapDataType(field.Field, opts)
 		if err != nil {
 			slog.Warn("error mapping data type",
 				slog.String("field", string(field.Desc.Name())),
@@ -162,8 +435,15 @@ func buildColumns(protoMessage *protogen.Message) ([]core.Column, error) {
 			continue
 		}
 
+		columnType = boundedType(columnType, fieldRules(field.Field).GetString(), opts)
+
+		columnType, err = autoIncrementType(field.Field, columnType)
+		if err != nil {
+			return nil, err
+		}
+
 		column := &core.Column{
-			Name: string(field.Desc.Name()),
+			Name: field.Column,
 			Type: columnType,
 		}
 
@@ -174,19 +454,184 @@ func buildColumns(protoMessage *protogen.Message) ([]core.Column, error) {
 		}
 
 		// Format default values appropriately based on type
-		if column.DefaultValue != "" {
-			if column.Type != core.IntegerType && column.Type != core.FloatType &&
-				column.Type != core.BooleanType {
-				column.DefaultValue = fmt.Sprintf("'%v'", column.DefaultValue)
-			}
+		switch {
+		case column.DefaultValue == "":
+		case field.Enum != nil && !field.Desc.IsList():
+			column.DefaultValue = enumDefault(field.Enum, column.DefaultValue, opts)
+		case !isUnquotedType(column.Type):
+			column.DefaultValue = opts.SQLDialect().QuoteLiteral(column.DefaultValue)
+		}
+
+		applyDescriptor(field.Field, column, opts)
+
+		// Only one member of a oneof is set, the others are always NULL, and
+		// fields embedded through an unset field are NULL too, so presence
+		// applies to none of them
+		if field.InOneof || field.Optional {
+			column.NotNull = false
+		} else if opts.FieldPresence {
+			applyPresence(field.Field, column, opts)
 		}
 
 		columns = append(columns, *column)
+
+		if isMoney(field.Field) {
+			columns = append(columns, core.Column{
+				Name:    column.Name + moneyCurrencySuffix,
+				Type:    core.TextType,
+				NotNull: column.NotNull,
+			})
+		}
 	}
 
 	return columns, nil
 }
 
+// boundedType returns the type of a string column, which the dialect may
+// store in a shorter type when a max_len rule bounds its length.
+func boundedType(
+	columnType core.ColumnType,
+	rules *validate.StringRules,
+	opts template.Options,
+) core.ColumnType {
+	if columnType != core.TextType {
+		return columnType
+	}
+
+	return opts.SQLDialect().StringType(rules.GetMaxLen())
+}
+
+// autoIncrementType returns the serial type of an auto-incremented column,
+// which must be an integer primary key without a default value.
+func autoIncrementType(field *protogen.Field, columnType core.ColumnType) (core.ColumnType, error) {
+	ext := fieldConstraints(field)
+	if !ext.GetAutoIncrement() {
+		return columnType, nil
+	}
+
+	if ext.GetPrimary() && ext.GetDefault() == "" {
+		switch columnType {
+		case core.IntegerType:
+			return core.SerialType, nil
+		case core.BigIntType:
+			return core.BigSerialType, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrInvalidAutoIncrement, field.Desc.FullName())
+}
+
+// columnField is a protobuf field stored in a column of a message table.
+type columnField struct {
+	*protogen.Field
+
+	// Column is the column name, prefixed with the names of the embedded
+	// fields leading to the field.
+	Column string
+	// Prefix is the prefix of the column name, empty for fields of the table
+	// message itself.
+	Prefix string
+	// InOneof reports whether the field is a member of a oneof, or is embedded
+	// through one.
+	InOneof bool
+	// Optional reports whether the field is embedded through a field that may
+	// be unset, leaving its column NULL.
+	Optional bool
+}
+
+// requiredField reports whether a field is always set: a key, a field required
+// by protovalidate or a proto2 required field.
+func requiredField(field *protogen.Field) bool {
+	return fieldConstraints(field).GetPrimary() || fieldRules(field).GetRequired() ||
+		field.Desc.Cardinality() == protoreflect.Required
+}
+
+// columnFields returns the fields of a message that are stored in columns of
+// its table. Fields of embedded messages are expanded in place, recursively,
+// and fields stored in child tables are left out.
+func columnFields(protoMessage *protogen.Message, opts template.Options) ([]columnField, error) {
+	return appendColumnFields(nil, protoMessage, "", nil, columnField{}, opts)
+}
+
+// appendColumnFields appends the column fields of a message, embedded through
+// the embedding field unless it is the table message itself.
+func appendColumnFields(
+	fields []columnField,
+	protoMessage *protogen.Message,
+	prefix string,
+	path []protoreflect.FullName,
+	embedding columnField,
+	opts template.Options,
+) ([]columnField, error) {
+	path = append(path, protoMessage.Desc.FullName())
+
+	for _, field := range protoMessage.Fields {
+		column := prefix + columnName(string(field.Desc.Name()), opts)
+
+		// Child tables are only built for the fields of the table message
+		if storedInChildTable(field) {
+			if prefix != "" {
+				return nil, fmt.Errorf("%w: embedded field %s", ErrNestedChildTable, column)
+			}
+
+			continue
+		}
+
+		stored := columnField{
+			Field:    field,
+			Column:   column,
+			Prefix:   prefix,
+			InOneof:  embedding.InOneof || isRealOneof(field.Oneof),
+			Optional: embedding.Optional,
+		}
+
+		if !fieldConstraints(field).GetEmbed() {
+			fields = append(fields, stored)
+
+			continue
+		}
+
+		switch {
+		case field.Message == nil || field.Desc.IsList() || field.Desc.IsMap():
+			slog.Warn("only singular message fields can be embedded", slog.String("field", column))
+
+			fields = append(fields, stored)
+		case slices.Contains(path, field.Message.Desc.FullName()):
+			slog.Warn("skip recursively embedded message", slog.String("field", column))
+		default:
+			if isRealOneof(field.Oneof) {
+				slog.Warn("embedded oneof member is not covered by the oneof check",
+					slog.String("field", column))
+			}
+
+			// The fields of an unset message are all NULL, whatever their
+			// own rules
+			stored.Optional = stored.Optional || !requiredField(field)
+
+			var err error
+
+			fields, err = appendColumnFields(fields, field.Message, column+"_", path, stored, opts)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+// isUnquotedType reports whether default values of a column type are SQL
+// literals that must not be quoted.
+func isUnquotedType(columnType core.ColumnType) bool {
+	switch columnType {
+	case core.SmallIntType, core.IntegerType, core.BigIntType, core.NumericUint64Type, core.NumericType,
+		core.RealType, core.DoublePrecisionType, core.BooleanType:
+		return true
+	default:
+		return false
+	}
+}
+
 // applyExtensions applies proto extensions to a column definition.
 func applyExtensions(opts protoreflect.ProtoMessage, column *core.Column) error {
 	if opts == nil {
@@ -216,42 +661,42 @@ func applyExtensions(opts protoreflect.ProtoMessage, column *core.Column) error
 		} else {
 			column.NotNull = column.NotNull || ext.GetRequired()
 
-			// Check for UUID type
-			stringRules := ext.GetString()
-			if stringRules != nil && stringRules.GetUuid() {
+			// Check for UUID type, either of the column or of its items
+			if ext.GetString().GetUuid() {
 				column.Type = core.UUIDType
 			}
+
+			if ext.GetRepeated().GetItems().GetString().GetUuid() {
+				column.Type = core.UUIDType.Array()
+			}
 		}
 	}
 
 	return nil
 }
 
-// buildConstraints extracts SQL constraints from protobuf message fields.
-func buildConstraints(protoMessage *protogen.Message) ([]core.Constraint, error) {
+// buildConstraints extracts SQL constraints from protobuf message fields and
+// the message-level table options.
+func (sb *SchemaBuilder) buildConstraints(
+	protoMessage *protogen.Message,
+	fields []columnField,
+) ([]core.Constraint, error) {
 	if protoMessage == nil {
 		return nil, ErrNilMessage
 	}
 
-	var constraints []core.Constraint
-
-	for _, field := range protoMessage.Fields {
-		opts := field.Desc.Options()
-		if !proto.HasExtension(opts, sqlcpb.E_Field) {
-			continue
-		}
-
-		ext, ok := proto.GetExtension(opts, sqlcpb.E_Field).(*sqlcpb.FieldConstraints)
-		if !ok {
-			slog.Warn(
-				"invalid extension type for field",
-				slog.String("field", string(field.Desc.Name())),
-			)
+	var (
+		constraints []core.Constraint
+		primaryKey  []string
+	)
 
+	for _, field := range fields {
+		ext := fieldConstraints(field.Field)
+		if ext == nil {
 			continue
 		}
 
-		fieldName := string(field.Desc.Name())
+		fieldName := field.Column
 
 		// Handle unique constraint
 		if ext.GetUnique() {
@@ -261,12 +706,9 @@ func buildConstraints(protoMessage *protogen.Message) ([]core.Constraint, error)
 			})
 		}
 
-		// Handle primary key constraint
+		// Collect primary key columns, several fields form a composite key
 		if ext.GetPrimary() {
-			constraints = append(constraints, core.Constraint{
-				Type:    core.PrimaryKeyConstraint,
-				Columns: []string{fieldName},
-			})
+			primaryKey = append(primaryKey, fieldName)
 		}
 
 		// Handle foreign key constraint
@@ -278,79 +720,337 @@ func buildConstraints(protoMessage *protogen.Message) ([]core.Constraint, error)
 				continue
 			}
 
+			table, err := sb.resolveReference(field.Field, parts[0])
+			if err != nil {
+				return nil, fmt.Errorf("resolving references of %s: %w", fieldName, err)
+			}
+
 			constraints = append(constraints, core.Constraint{
 				Type:    core.ForeignKeyConstraint,
 				Columns: []string{fieldName},
 				References: &core.Reference{
-					Table:    parts[0],
-					Columns:  []string{parts[1]},
-					OnDelete: core.ForeignKeyActionNoAction,
-					OnUpdate: core.ForeignKeyActionNoAction,
+					Table:      table,
+					Columns:    []string{columnName(parts[1], sb.Options)},
+					OnDelete:   mapForeignKeyAction(ext.GetOnDelete()),
+					OnUpdate:   mapForeignKeyAction(ext.GetOnUpdate()),
+					Deferrable: ext.GetDeferrable(),
 				},
 			})
 		}
 	}
 
+	ext := tableOptions(protoMessage)
+
+	if len(ext.GetPrimaryKey()) > 0 {
+		if len(primaryKey) > 0 {
+			return nil, ErrMultiplePrimaryKeys
+		}
+
+		primaryKey = columnNames(ext.GetPrimaryKey(), sb.Options)
+	}
+
+	for _, unique := range ext.GetUnique() {
+		constraints = append(constraints, core.Constraint{
+			Type:    core.UniqueConstraint,
+			Columns: columnNames(unique.GetColumns(), sb.Options),
+		})
+	}
+
+	if len(primaryKey) > 0 {
+		constraints = append([]core.Constraint{{
+			Type:    core.PrimaryKeyConstraint,
+			Columns: primaryKey,
+		}}, constraints...)
+	}
+
 	return constraints, nil
 }
 
+// checkConstraintColumns verifies that every column named by a table
+// constraint or index exists, and marks primary key columns as NOT NULL.
+func checkConstraintColumns(table *core.Table) error {
+	for _, constraint := range table.Constraints {
+		for _, name := range constraint.Columns {
+			column := table.ColumnByName(name)
+			if column == nil {
+				return fmt.Errorf("%w: %s", ErrColumnNotFound, name)
+			}
+
+			if constraint.Type == core.PrimaryKeyConstraint {
+				column.NotNull = true
+			}
+
+			if constraint.Type == core.ForeignKeyConstraint && column.NotNull &&
+				(constraint.References.OnDelete == core.ForeignKeyActionSetNull ||
+					constraint.References.OnUpdate == core.ForeignKeyActionSetNull) {
+				return fmt.Errorf("%w: %s", ErrSetNullOnNotNull, name)
+			}
+		}
+	}
+
+	for _, index := range table.Indexes {
+		for _, name := range slices.Concat(index.Columns, index.Include) {
+			if table.ColumnByName(name) == nil {
+				return fmt.Errorf("%w: %s in index %s", ErrColumnNotFound, name, index.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// buildIndexes extracts SQL indexes from field-level and message-level index
+// options.
+func buildIndexes(
+	protoMessage *protogen.Message,
+	fields []columnField,
+	table string,
+	opts template.Options,
+) ([]core.Index, error) {
+	if protoMessage == nil {
+		return nil, ErrNilMessage
+	}
+
+	var indexes []core.Index
+
+	for _, field := range fields {
+		ext := fieldConstraints(field.Field)
+		if ext.GetIndex() == nil {
+			continue
+		}
+
+		indexes = append(indexes, buildIndex(table, ext.GetIndex(), field.Column, opts))
+	}
+
+	for _, ext := range tableOptions(protoMessage).GetIndex() {
+		if len(ext.GetColumns()) == 0 && ext.GetExpression() == "" {
+			return nil, errors.New("index without columns or expression")
+		}
+
+		indexes = append(indexes, buildIndex(table, ext, "", opts))
+	}
+
+	return indexes, nil
+}
+
+// buildIndex converts an index option to a SQL index on the given table. For
+// field-level indexes, column is the annotated column and replaces the
+// declared columns.
+func buildIndex(table string, ext *sqlcpb.Index, column string, opts template.Options) core.Index {
+	index := core.Index{
+		Columns:    columnNames(ext.GetColumns(), opts),
+		Expression: ext.GetExpression(),
+		Unique:     ext.GetUnique(),
+		Method:     mapIndexMethod(ext.GetMethod()),
+		Where:      ext.GetWhere(),
+		Include:    columnNames(ext.GetInclude(), opts),
+	}
+
+	switch {
+	case index.Expression != "":
+		index.Columns = nil
+	case column != "":
+		index.Columns = []string{column}
+	}
+
+	index.Name = cmp.Or(ext.GetName(), indexName(table, index))
+
+	return index
+}
+
+// mapForeignKeyAction converts a protobuf foreign key action to a SQL
+// referential action, defaulting to NO ACTION.
+func mapForeignKeyAction(action sqlcpb.ForeignKeyAction) core.ForeignKeyAction {
+	switch action {
+	case sqlcpb.ForeignKeyAction_FOREIGN_KEY_ACTION_RESTRICT:
+		return core.ForeignKeyActionRestrict
+	case sqlcpb.ForeignKeyAction_FOREIGN_KEY_ACTION_CASCADE:
+		return core.ForeignKeyActionCascade
+	case sqlcpb.ForeignKeyAction_FOREIGN_KEY_ACTION_SET_NULL:
+		return core.ForeignKeyActionSetNull
+	case sqlcpb.ForeignKeyAction_FOREIGN_KEY_ACTION_SET_DEFAULT:
+		return core.ForeignKeyActionSetDefault
+	case sqlcpb.ForeignKeyAction_FOREIGN_KEY_ACTION_UNSPECIFIED,
+		sqlcpb.ForeignKeyAction_FOREIGN_KEY_ACTION_NO_ACTION:
+		return core.ForeignKeyActionNoAction
+	default:
+		return core.ForeignKeyActionNoAction
+	}
+}
+
+// mapIndexMethod converts a protobuf index method to a SQL access method.
+func mapIndexMethod(method sqlcpb.IndexMethod) core.IndexMethod {
+	switch method {
+	case sqlcpb.IndexMethod_INDEX_METHOD_BTREE:
+		return core.IndexMethodBTree
+	case sqlcpb.IndexMethod_INDEX_METHOD_GIN:
+		return core.IndexMethodGIN
+	case sqlcpb.IndexMethod_INDEX_METHOD_GIST:
+		return core.IndexMethodGiST
+	case sqlcpb.IndexMethod_INDEX_METHOD_HASH:
+		return core.IndexMethodHash
+	case sqlcpb.IndexMethod_INDEX_METHOD_BRIN:
+		return core.IndexMethodBRIN
+	case sqlcpb.IndexMethod_INDEX_METHOD_UNSPECIFIED:
+		return ""
+	default:
+		return ""
+	}
+}
+
+// indexName derives a deterministic index name from the table name and the
+// indexed columns or expression, following the PostgreSQL table_column_idx
+// convention.
+func indexName(table string, index core.Index) string {
+	parts := []string{table}
+	if index.Expression != "" {
+		parts = append(parts, strings.FieldsFunc(index.Expression, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	} else {
+		parts = append(parts, index.Columns...)
+	}
+
+	return identifierName(append(parts, "idx")...)
+}
+
+// identifierName joins name parts with underscores. Names over the maximum
+// identifier length are truncated and end with a hash of the full name, so
+// that long names sharing a prefix stay distinct.
+func identifierName(parts ...string) string {
+	name := strings.Join(parts, "_")
+	if len(name) <= maxIdentifierLength {
+		return name
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(name))
+	suffix := fmt.Sprintf("_%08x", hash.Sum32())
+
+	prefix := name[:maxIdentifierLength-len(suffix)]
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+
+	return prefix + suffix
+}
+
 // mapDataType converts protobuf field types to SQL column types.
-func mapDataType(field *protogen.Field) (core.ColumnType, error) {
+func mapDataType(field *protogen.Field, opts template.Options) (core.ColumnType, error) {
 	if field == nil {
 		return "", errors.New("nil field provided")
 	}
 
+	if field.Desc.IsMap() {
+		return mapMapType(field)
+	}
+
+	if field.Desc.IsList() {
+		elementType, err := mapElementType(field, opts)
+		if err != nil {
+			return "", err
+		}
+
+		return elementType.Array(), nil
+	}
+
+	return mapElementType(field, opts)
+}
+
+// mapElementType converts the type of a single protobuf field value, ignoring
+// its cardinality, to a SQL column type.
+func mapElementType(field *protogen.Field, opts template.Options) (core.ColumnType, error) {
 	switch field.Desc.Kind() {
 	case protoreflect.BoolKind:
 		return core.BooleanType, nil
 	case protoreflect.BytesKind:
-		return core.BytesType, nil
-	case protoreflect.FloatKind, protoreflect.DoubleKind:
-		return core.FloatType, nil
+		return core.ByteaType, nil
+	case protoreflect.FloatKind:
+		return core.RealType, nil
+	case protoreflect.DoubleKind:
+		return core.DoublePrecisionType, nil
 	case protoreflect.StringKind:
-		return mapStringType(field)
-	case protoreflect.Fixed32Kind, protoreflect.Fixed64Kind,
-		protoreflect.Int32Kind, protoreflect.Int64Kind,
-		protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind,
-		protoreflect.Sint32Kind, protoreflect.Sint64Kind,
-		protoreflect.Uint32Kind, protoreflect.Uint64Kind:
+		return core.TextType, nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
 		return core.IntegerType, nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		// Unsigned 32-bit values do not fit in a signed INTEGER
+		return core.BigIntType, nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return core.NumericUint64Type, nil
 	case protoreflect.EnumKind:
-		return core.ColumnType(field.Enum.Desc.Name()), nil
+		return enumColumnType(field.Enum, opts), nil
 	case protoreflect.MessageKind, protoreflect.GroupKind:
-		return mapMessageType(field)
+		return mapMessageType(field, opts)
 	default:
-		return core.BytesType, nil
+		return core.ByteaType, nil
 	}
 }
 
-func mapStringType(field *protogen.Field) (core.ColumnType, error) {
-	if field.Desc.Cardinality() == protoreflect.Repeated {
-		return core.TextArrayType, nil
-	}
-
-	return core.TextType, nil
-}
-
-func mapMessageType(field *protogen.Field) (core.ColumnType, error) {
+func mapMessageType(field *protogen.Field, opts template.Options) (core.ColumnType, error) {
 	if field.Message == nil || field.Message.Desc == nil {
 		return "", errors.New("message field has nil descriptor")
 	}
 
+	if value := wrapperValue(field); value != nil {
+		return mapDataType(value, opts)
+	}
+
 	switch field.Message.Desc.FullName() {
 	case "google.protobuf.Timestamp":
 		return core.TimestampType, nil
-	case "google.protobuf.Struct":
+	case "google.protobuf.Duration":
+		return core.IntervalType, nil
+	case "google.protobuf.Struct", "google.protobuf.Value",
+		"google.protobuf.ListValue", "google.protobuf.Any":
 		return core.JSONBType, nil
+	case "google.type.Date":
+		return core.DateType, nil
+	case "google.type.TimeOfDay":
+		return core.TimeType, nil
+	case "google.type.Decimal", moneyFullName:
+		return core.NumericType, nil
+	case "google.type.LatLng":
+		return core.PointType, nil
+	default:
+		// Unknown messages are stored in their wire format
+		return core.ByteaType, nil
+	}
+}
+
+// wrapperValue returns the value field of a google.protobuf wrapper message
+// field, or nil if the field is not a wrapper.
+func wrapperValue(field *protogen.Field) *protogen.Field {
+	if field.Message == nil || len(field.Message.Fields) == 0 {
+		return nil
+	}
+
+	switch field.Message.Desc.FullName() {
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue",
+		"google.protobuf.BytesValue":
+		return field.Message.Fields[0]
 	default:
-		return core.BytesType, nil
+		return nil
 	}
 }
 
-// GenerateSchema creates a SQL schema file from the accumulated schema definition.
+// isMoney reports whether a field holds a google.type.Money, which is stored
+// as an amount column plus a currency column.
+func isMoney(field *protogen.Field) bool {
+	return field.Message != nil && !field.Desc.IsList() &&
+		field.Message.Desc.FullName() == moneyFullName
+}
+
+// GenerateSchema creates a SQL schema file from the accumulated schema definition,
+// listing the proto files it was built from as sources.
 func GenerateSchema(
 	p *protogen.Plugin,
 	schema core.Schema,
+	sources []string,
 	tmpl *template.Templates,
 	opts template.Options,
 ) error {
@@ -358,14 +1058,15 @@ func GenerateSchema(
 		return errors.New("nil plugin provided")
 	}
 
-	slog.Debug("generating schema.sql")
+	name := cmp.Or(opts.SchemaFile, template.DefaultSchemaFile)
+	slog.Debug("generating schema", slog.String("name", name))
 
-	gf := p.NewGeneratedFile("schema.sql", "")
+	gf := p.NewGeneratedFile(name, "")
 
 	err := tmpl.ApplySchema(gf, &template.SchemaParams{
 		Schema:       schema,
 		Options:      opts,
-		HeaderParams: template.HeaderParams{},
+		HeaderParams: template.HeaderParams{Sources: sources},
 	})
 	if err != nil {
 		gf.Skip()
@@ -377,11 +1078,16 @@ func GenerateSchema(
 	return nil
 }
 
-// GenerateQueries creates SQL query files for each message in the schema.
+// GenerateQueries creates the SQL query files, one per proto file or a single
+// one depending on the layout, holding the queries of the messages in
+// declaration order.
 func GenerateQueries(
 	p *protogen.Plugin,
 	schema core.Schema,
-	filesByMessage map[string]*protogen.File,
+	messagesByFile map[string][]string,
+	queryNames map[string]string,
+	tablesByMessage map[string]string,
+	childrenByMessage map[string][]ChildTable,
 	tmpl *template.Templates,
 	opts template.Options,
 ) error {
@@ -389,42 +1095,130 @@ func GenerateQueries(
 		return errors.New("nil plugin provided")
 	}
 
-	for message, protoFile := range filesByMessage {
-		if protoFile == nil {
-			slog.Warn("nil proto file for message", slog.String("message", message))
+	for _, file := range queryFiles(p, messagesByFile, opts.Layout) {
+		slog.Debug("generating queries in", slog.String("name", file.name))
 
-			continue
+		gf := p.NewGeneratedFile(file.name, file.importPath)
+
+		err := tmpl.ApplyHeader(gf, &template.HeaderParams{Sources: file.sources})
+		for _, message := range file.messages {
+			if err != nil {
+				break
+			}
+
+			err = applyQueries(
+				gf,
+				schema,
+				queryNames[message],
+				tablesByMessage[message],
+				childrenByMessage[message],
+				tmpl,
+				opts,
+			)
 		}
 
-		name := protoFile.Proto.GetName()
-		slog.Debug("processing queries for file", slog.String("name", name))
-		slog.Debug(
-			"generating queries in",
-			slog.String("name", protoFile.GeneratedFilenamePrefix+".sql"),
-		)
+		if err != nil {
+			gf.Skip()
+			p.Error(err)
+		}
+	}
+
+	return nil
+}
+
+// queryFile is a generated query file along with the proto files and messages
+// whose queries it holds.
+type queryFile struct {
+	name       string
+	importPath protogen.GoImportPath
+	sources    []string
+	messages   []string
+}
+
+// queryFiles groups the messages of the generated proto files into query
+// files following the layout.
+func queryFiles(
+	p *protogen.Plugin,
+	messagesByFile map[string][]string,
+	layout template.Layout,
+) []*queryFile {
+	var files []*queryFile
+
+	for _, protoFile := range p.Files {
+		name := protoFile.Desc.Path()
+
+		messages := messagesByFile[name]
+		if !protoFile.Generate || len(messages) == 0 {
+			continue
+		}
 
-		gf := p.NewGeneratedFile(protoFile.GeneratedFilenamePrefix+".sql", protoFile.GoImportPath)
+		if layout == template.LayoutSingle {
+			if len(files) == 0 {
+				files = append(files, &queryFile{name: template.SingleQueryFile})
+			}
 
-		table := schema.TableByName(message)
-		if table == nil {
-			slog.Warn("table not found for message", slog.String("message", message))
+			files[0].sources = append(files[0].sources, name)
+			files[0].messages = append(files[0].messages, messages...)
 
 			continue
 		}
 
-		err := tmpl.ApplyCrud(gf, &template.CrudParams{
-			GoName:       message,
-			PrimaryKey:   table.PrimaryKey(),
-			Table:        *table,
-			Options:      opts,
-			HeaderParams: template.HeaderParams{Sources: []string{protoFile.Proto.GetName()}},
+		files = append(files, &queryFile{
+			name:       protoFile.GeneratedFilenamePrefix + ".sql",
+			importPath: protoFile.GoImportPath,
+			sources:    []string{name},
+			messages:   messages,
 		})
-		if err != nil {
-			gf.Skip()
-			p.Error(err)
+	}
+
+	return files
+}
+
+// applyQueries writes the queries of a message and of its child tables.
+func applyQueries(
+	w io.Writer,
+	schema core.Schema,
+	message string,
+	tableName string,
+	children []ChildTable,
+	tmpl *template.Templates,
+	opts template.Options,
+) error {
+	table := schema.TableByName(tableName)
+	if table == nil {
+		slog.Warn("table not found for message", slog.String("message", message))
+
+		return nil
+	}
+
+	err := tmpl.ApplyCrud(w, &template.CrudParams{
+		GoName:     message,
+		PrimaryKey: table.PrimaryKey(),
+		Table:      *table,
+		Options:    opts,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		childTable := schema.TableByName(child.Table)
+		if childTable == nil {
+			slog.Warn("table not found for child", slog.String("table", child.Table))
 
 			continue
 		}
+
+		err := tmpl.ApplyChild(w, &template.ChildParams{
+			GoName:    child.GoName,
+			ParentKey: child.ParentKey,
+			Key:       child.Key,
+			Table:     *childTable,
+			Options:   opts,
+		})
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
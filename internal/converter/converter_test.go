@@ -0,0 +1,2324 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package converter_test
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/converter"
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/core"
+	sqlcpb "github.com/pablojimpas/protoc-gen-sqlc/internal/gen/sqlc"
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/sqlc/template"
+)
+
+// newPlugin creates a plugin whose request generates the given file, along
+// with the well-known, protovalidate and sqlc files it may import and any
+// extra dependencies.
+func newPlugin(
+	t *testing.T,
+	file *descriptorpb.FileDescriptorProto,
+	deps ...*descriptorpb.FileDescriptorProto,
+) *protogen.Plugin {
+	t.Helper()
+
+	var (
+		files []*descriptorpb.FileDescriptorProto
+		seen  = make(map[string]bool)
+		add   func(fd protoreflect.FileDescriptor)
+	)
+
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+
+		seen[fd.Path()] = true
+
+		for i := range fd.Imports().Len() {
+			add(fd.Imports().Get(i).FileDescriptor)
+		}
+
+		files = append(files, protodesc.ToFileDescriptorProto(fd))
+	}
+
+	add(validate.File_buf_validate_validate_proto)
+	add(sqlcpb.File_sqlc_sqlc_proto)
+
+	for _, dep := range file.GetDependency() {
+		if slices.ContainsFunc(deps, func(d *descriptorpb.FileDescriptorProto) bool {
+			return d.GetName() == dep
+		}) {
+			continue
+		}
+
+		fd, err := protoregistry.GlobalFiles.FindFileByPath(dep)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		add(fd)
+	}
+
+	files = append(files, deps...)
+
+	file.Options = &descriptorpb.FileOptions{GoPackage: proto.String("example.com/test")}
+
+	p, err := protogen.Options{}.New(&pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		ProtoFile:      append(files, file),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+// buildSchema runs the schema builder over a single file.
+func buildSchema(
+	t *testing.T,
+	file *descriptorpb.FileDescriptorProto,
+	opts template.Options,
+	deps ...*descriptorpb.FileDescriptorProto,
+) *converter.SchemaBuilder {
+	t.Helper()
+
+	sb := converter.NewSchemaBuilder(opts)
+	if err := sb.Build(newPlugin(t, file, deps...)); err != nil {
+		t.Fatal(err)
+	}
+
+	return sb
+}
+
+// field returns a proto3 field descriptor with the given options.
+func field(
+	name string,
+	number int32,
+	typ descriptorpb.FieldDescriptorProto_Type,
+	opts *descriptorpb.FieldOptions,
+) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     typ.Enum(),
+		Options:  opts,
+	}
+}
+
+// validateOpts returns field options carrying protovalidate rules.
+func validateOpts(rules *validate.FieldRules) *descriptorpb.FieldOptions {
+	opts := &descriptorpb.FieldOptions{}
+	proto.SetExtension(opts, validate.E_Field, rules)
+
+	return opts
+}
+
+func TestBuildTableOptions(t *testing.T) {
+	t.Parallel()
+
+	message := func(
+		name string,
+		table *sqlcpb.TableOptions,
+		fields ...*descriptorpb.FieldDescriptorProto,
+	) *descriptorpb.DescriptorProto {
+		opts := &descriptorpb.MessageOptions{}
+		if table != nil {
+			proto.SetExtension(opts, sqlcpb.E_Table, table)
+		}
+
+		return &descriptorpb.DescriptorProto{Name: proto.String(name), Field: fields, Options: opts}
+	}
+
+	id := field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+		&sqlcpb.FieldConstraints{Primary: true},
+	))
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/tables.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			message("Author", &sqlcpb.TableOptions{Name: "writers", Include: true}, id),
+			message("BookDraft", &sqlcpb.TableOptions{Skip: true, Include: true}, id),
+			message("Book", &sqlcpb.TableOptions{Include: true},
+				id,
+				field("author_id", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+					&sqlcpb.FieldConstraints{References: "Author.id"},
+				)),
+			),
+			message("ListBooksRequest", nil),
+		},
+	}
+
+	for _, tt := range []struct {
+		name   string
+		opts   template.Options
+		tables []string
+		ref    string
+	}{
+		{"default", template.Options{}, []string{"Book", "ListBooksRequest", "writers"}, "writers"},
+		{
+			"plural",
+			template.Options{Naming: core.NamingPlural},
+			[]string{"books", "list_books_requests", "writers"},
+			"writers",
+		},
+		{"only annotated", template.Options{OnlyAnnotated: true}, []string{"Book", "writers"}, "writers"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sb := buildSchema(t, proto.CloneOf(file), tt.opts)
+
+			tables := make([]string, 0, len(sb.Schema.Tables))
+			for _, table := range sb.Schema.Tables {
+				tables = append(tables, table.Name)
+			}
+
+			slices.Sort(tables)
+
+			if !slices.Equal(tables, tt.tables) {
+				t.Errorf("tables = %v, want %v", tables, tt.tables)
+			}
+
+			book := sb.Schema.TableByName(tt.tables[0])
+			if book == nil {
+				t.Fatalf("table %s not built", tt.tables[0])
+			}
+
+			if !slices.ContainsFunc(book.Constraints, func(c core.Constraint) bool {
+				return c.Type == core.ForeignKeyConstraint && c.References.Table == tt.ref
+			}) {
+				t.Errorf("constraints = %+v, want a foreign key to %s", book.Constraints, tt.ref)
+			}
+		})
+	}
+
+	// A reference to a message without a table cannot be satisfied
+	skipped := proto.CloneOf(file)
+	skipped.MessageType[0].Options = nil
+
+	err := converter.NewSchemaBuilder(template.Options{OnlyAnnotated: true}).Build(newPlugin(t, skipped))
+	if !errors.Is(err, converter.ErrTableNotFound) {
+		t.Errorf("Build error = %v, want %v", err, converter.ErrTableNotFound)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	t.Parallel()
+
+	id := field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+		&sqlcpb.FieldConstraints{Primary: true},
+	))
+	name := field("name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil)
+
+	for _, tt := range []struct {
+		name   string
+		table  *sqlcpb.TableOptions
+		fields []*descriptorpb.FieldDescriptorProto
+		want   error
+	}{
+		{
+			"unknown key column",
+			&sqlcpb.TableOptions{PrimaryKey: []string{"id", "nmae"}},
+			[]*descriptorpb.FieldDescriptorProto{name},
+			converter.ErrColumnNotFound,
+		},
+		{
+			"unknown unique column",
+			&sqlcpb.TableOptions{Unique: []*sqlcpb.UniqueConstraint{{Columns: []string{"nmae"}}}},
+			[]*descriptorpb.FieldDescriptorProto{id, name},
+			converter.ErrColumnNotFound,
+		},
+		{
+			"unknown index column",
+			&sqlcpb.TableOptions{Index: []*sqlcpb.Index{{Columns: []string{"nmae"}}}},
+			[]*descriptorpb.FieldDescriptorProto{id, name},
+			converter.ErrColumnNotFound,
+		},
+		{
+			"two primary keys",
+			&sqlcpb.TableOptions{PrimaryKey: []string{"name"}},
+			[]*descriptorpb.FieldDescriptorProto{id, name},
+			converter.ErrMultiplePrimaryKeys,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := &descriptorpb.MessageOptions{}
+			proto.SetExtension(opts, sqlcpb.E_Table, tt.table)
+
+			file := &descriptorpb.FileDescriptorProto{
+				Name:       proto.String("test/errors.proto"),
+				Package:    proto.String("test"),
+				Syntax:     proto.String("proto3"),
+				Dependency: []string{"sqlc/sqlc.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{{
+					Name:    proto.String("Item"),
+					Field:   tt.fields,
+					Options: opts,
+				}},
+			}
+
+			sb := converter.NewSchemaBuilder(template.Options{})
+
+			err := sb.Build(newPlugin(t, file))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Build error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildIndexes(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("a_very_long_column_name_", 2)
+
+	opts := &descriptorpb.MessageOptions{}
+	proto.SetExtension(opts, sqlcpb.E_Table, &sqlcpb.TableOptions{Index: []*sqlcpb.Index{
+		{Expression: "lower(email)", Unique: true, Where: "deleted IS NULL"},
+		{Columns: []string{"org_id", "email"}, Include: []string{"deleted"}},
+		{Columns: []string{long + "one", long + "two"}},
+		{Columns: []string{long + "one", long + "three"}},
+	}})
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/indexes.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("User"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("org_id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+					// Columns of field-level indexes are ignored
+					&sqlcpb.FieldConstraints{Index: &sqlcpb.Index{Columns: []string{"email"}}},
+				)),
+				field("email", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, sqlcOpts(
+					&sqlcpb.FieldConstraints{Index: &sqlcpb.Index{
+						Name:   "user_email_hash",
+						Method: sqlcpb.IndexMethod_INDEX_METHOD_HASH,
+					}},
+				)),
+				field("deleted", 3, descriptorpb.FieldDescriptorProto_TYPE_BOOL, nil),
+				field(long+"one", 4, descriptorpb.FieldDescriptorProto_TYPE_INT32, nil),
+				field(long+"two", 5, descriptorpb.FieldDescriptorProto_TYPE_INT32, nil),
+				field(long+"three", 6, descriptorpb.FieldDescriptorProto_TYPE_INT32, nil),
+			},
+			Options: opts,
+		}},
+	}
+
+	table := buildSchema(t, file, template.Options{}).Schema.TableByName("User")
+	if table == nil {
+		t.Fatal("table User not built")
+	}
+
+	want := []core.Index{
+		{Name: "User_org_id_idx", Columns: []string{"org_id"}},
+		{Name: "user_email_hash", Columns: []string{"email"}, Method: core.IndexMethodHash},
+		{
+			Name:       "User_lower_email_idx",
+			Expression: "lower(email)",
+			Unique:     true,
+			Where:      "deleted IS NULL",
+		},
+		{Name: "User_org_id_email_idx", Columns: []string{"org_id", "email"}, Include: []string{"deleted"}},
+	}
+	if !reflect.DeepEqual(table.Indexes[:len(want)], want) {
+		t.Errorf("indexes = %+v, want %+v", table.Indexes[:len(want)], want)
+	}
+
+	// Long names are truncated to distinct names
+	first, second := table.Indexes[len(want)].Name, table.Indexes[len(want)+1].Name
+	if len(first) != 63 || len(second) != 63 || first == second {
+		t.Errorf("long index names = %q, %q, want distinct names of 63 bytes", first, second)
+	}
+}
+
+func TestBuildForeignKeyActions(t *testing.T) {
+	t.Parallel()
+
+	reference := func(
+		name string,
+		number int32,
+		onDelete, onUpdate sqlcpb.ForeignKeyAction,
+	) *descriptorpb.FieldDescriptorProto {
+		f := field(name, number, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+			&sqlcpb.FieldConstraints{
+				References: "Author.id",
+				OnDelete:   onDelete,
+				OnUpdate:   onUpdate,
+				Deferrable: number == 1,
+			},
+		))
+		f.Proto3Optional = proto.Bool(true)
+		f.OneofIndex = proto.Int32(number - 1)
+
+		return f
+	}
+
+	fields := []*descriptorpb.FieldDescriptorProto{
+		reference("unspecified", 1,
+			sqlcpb.ForeignKeyAction_FOREIGN_KEY_ACTION_UNSPECIFIED,
+			sqlcpb.ForeignKeyAction_FOREIGN_KEY_ACTION_NO_ACTION),
+		reference("restrict", 2,
+			sqlcpb.ForeignKeyAction_FOREIGN_KEY_ACTION_RESTRICT,
+			sqlcpb.ForeignKeyAction_FOREIGN_KEY_ACTION_CASCADE),
+		reference("set_null", 3,
+			sqlcpb.ForeignKeyAction_FOREIGN_KEY_ACTION_SET_NULL,
+			sqlcpb.ForeignKeyAction_FOREIGN_KEY_ACTION_SET_DEFAULT),
+	}
+
+	var oneofs []*descriptorpb.OneofDescriptorProto
+	for _, f := range fields {
+		oneofs = append(oneofs, &descriptorpb.OneofDescriptorProto{Name: proto.String("_" + f.GetName())})
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/actions.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Author"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+						&sqlcpb.FieldConstraints{Primary: true},
+					)),
+				},
+			},
+			{Name: proto.String("Book"), Field: fields, OneofDecl: oneofs},
+		},
+	}
+
+	// Fields with explicit presence stay nullable, so SET NULL is allowed
+	book := buildSchema(t, file, template.Options{FieldPresence: true}).Schema.TableByName("Book")
+	if book == nil {
+		t.Fatal("table Book not built")
+	}
+
+	want := []core.Reference{
+		{
+			OnDelete:   core.ForeignKeyActionNoAction,
+			OnUpdate:   core.ForeignKeyActionNoAction,
+			Deferrable: true,
+		},
+		{OnDelete: core.ForeignKeyActionRestrict, OnUpdate: core.ForeignKeyActionCascade},
+		{OnDelete: core.ForeignKeyActionSetNull, OnUpdate: core.ForeignKeyActionSetDefault},
+	}
+
+	if len(book.Constraints) != len(want) {
+		t.Fatalf("constraints = %+v, want %d foreign keys", book.Constraints, len(want))
+	}
+
+	for i, constraint := range book.Constraints {
+		got := *constraint.References
+		if got.Table != "Author" || !slices.Equal(got.Columns, []string{"id"}) {
+			t.Errorf("constraint %d references %s%v, want Author[id]", i, got.Table, got.Columns)
+		}
+
+		got.Table, got.Columns = "", nil
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("constraint %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	// Without explicit presence the column is NOT NULL, which SET NULL violates
+	implicit := proto.CloneOf(file)
+	implicit.MessageType[1].Field[2].Proto3Optional = nil
+	implicit.MessageType[1].Field[2].OneofIndex = nil
+	implicit.MessageType[1].OneofDecl = implicit.MessageType[1].OneofDecl[:2]
+
+	sb := converter.NewSchemaBuilder(template.Options{FieldPresence: true})
+
+	err := sb.Build(newPlugin(t, implicit))
+	if !errors.Is(err, converter.ErrSetNullOnNotNull) {
+		t.Errorf("Build error = %v, want %v", err, converter.ErrSetNullOnNotNull)
+	}
+}
+
+func TestBuildAutoIncrement(t *testing.T) {
+	t.Parallel()
+
+	key := func(
+		name string,
+		typ descriptorpb.FieldDescriptorProto_Type,
+	) *descriptorpb.FieldDescriptorProto {
+		return field(name, 1, typ, sqlcOpts(&sqlcpb.FieldConstraints{Primary: true, AutoIncrement: true}))
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/serial.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Author"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					key("id", descriptorpb.FieldDescriptorProto_TYPE_INT32),
+				},
+			},
+			{
+				Name: proto.String("Book"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					key("id", descriptorpb.FieldDescriptorProto_TYPE_INT64),
+				},
+			},
+		},
+	}
+
+	schema := buildSchema(t, file, template.Options{}).Schema
+
+	for table, want := range map[string]core.ColumnType{
+		"Author": core.SerialType,
+		"Book":   core.BigSerialType,
+	} {
+		if got := schema.TableByName(table).ColumnByName("id"); got == nil || got.Type != want {
+			t.Errorf("column %s.id = %+v, want type %s", table, got, want)
+		}
+	}
+
+	for name, constraints := range map[string]*sqlcpb.FieldConstraints{
+		"not a key":   {AutoIncrement: true},
+		"not integer": {Primary: true, AutoIncrement: true},
+		"default":     {Primary: true, AutoIncrement: true, Default: "1"},
+	} {
+		invalid := proto.CloneOf(file)
+		invalid.MessageType[1].Field[0] = field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64,
+			sqlcOpts(constraints))
+
+		if name == "not integer" {
+			invalid.MessageType[1].Field[0].Type = descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+		}
+
+		err := converter.NewSchemaBuilder(template.Options{}).Build(newPlugin(t, invalid))
+		if !errors.Is(err, converter.ErrInvalidAutoIncrement) {
+			t.Errorf("%s: Build error = %v, want %v", name, err, converter.ErrInvalidAutoIncrement)
+		}
+	}
+}
+
+func TestBuildChecks(t *testing.T) {
+	t.Parallel()
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/book.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"buf/validate/validate.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Book"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("year", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, validateOpts(
+					validate.FieldRules_builder{Int32: validate.Int32Rules_builder{
+						Gte: proto.Int32(1450),
+						Lte: proto.Int32(2100),
+					}.Build()}.Build(),
+				)),
+				field("isbn", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, validateOpts(
+					validate.FieldRules_builder{String: validate.StringRules_builder{
+						MinLen:  proto.Uint64(10),
+						Pattern: proto.String("^[0-9X]+$"),
+						NotIn:   []string{"0000000000"},
+					}.Build()}.Build(),
+				)),
+				field("title", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil),
+			},
+		}},
+	}
+
+	table := buildSchema(t, file, template.Options{}).Schema.TableByName("Book")
+	if table == nil {
+		t.Fatal("table Book not built")
+	}
+
+	want := []core.Constraint{
+		{
+			Name:       "Book_year_check",
+			Type:       core.CheckConstraint,
+			Columns:    []string{"year"},
+			Expression: "year >= 1450 AND year <= 2100",
+		},
+		{
+			Name:    "Book_isbn_check",
+			Type:    core.CheckConstraint,
+			Columns: []string{"isbn"},
+			Expression: "char_length(isbn) >= 10 AND isbn ~ '^[0-9X]+$' AND " +
+				"isbn NOT IN ('0000000000')",
+		},
+	}
+
+	if len(table.Constraints) != len(want) {
+		t.Fatalf("got %d constraints, want %d: %+v", len(table.Constraints), len(want), table.Constraints)
+	}
+
+	for i, c := range table.Constraints {
+		if c.Name != want[i].Name || c.Type != want[i].Type || c.Expression != want[i].Expression {
+			t.Errorf("constraint %d = %+v, want %+v", i, c, want[i])
+		}
+	}
+}
+
+func TestMapDataType(t *testing.T) {
+	t.Parallel()
+
+	type fd = descriptorpb.FieldDescriptorProto
+
+	tests := []struct {
+		field *fd
+		want  core.ColumnType
+	}{
+		{field("f_bool", 1, descriptorpb.FieldDescriptorProto_TYPE_BOOL, nil), core.BooleanType},
+		{field("f_enum", 2, descriptorpb.FieldDescriptorProto_TYPE_ENUM, nil), `"Kind"`},
+		{field("f_int32", 3, descriptorpb.FieldDescriptorProto_TYPE_INT32, nil), core.IntegerType},
+		{field("f_sint32", 4, descriptorpb.FieldDescriptorProto_TYPE_SINT32, nil), core.IntegerType},
+		{
+			field("f_sfixed32", 5, descriptorpb.FieldDescriptorProto_TYPE_SFIXED32, nil),
+			core.IntegerType,
+		},
+		{field("f_uint32", 6, descriptorpb.FieldDescriptorProto_TYPE_UINT32, nil), core.BigIntType},
+		{field("f_fixed32", 7, descriptorpb.FieldDescriptorProto_TYPE_FIXED32, nil), core.BigIntType},
+		{field("f_int64", 8, descriptorpb.FieldDescriptorProto_TYPE_INT64, nil), core.BigIntType},
+		{field("f_sint64", 9, descriptorpb.FieldDescriptorProto_TYPE_SINT64, nil), core.BigIntType},
+		{
+			field("f_sfixed64", 10, descriptorpb.FieldDescriptorProto_TYPE_SFIXED64, nil),
+			core.BigIntType,
+		},
+		{
+			field("f_uint64", 11, descriptorpb.FieldDescriptorProto_TYPE_UINT64, nil),
+			core.NumericUint64Type,
+		},
+		{
+			field("f_fixed64", 12, descriptorpb.FieldDescriptorProto_TYPE_FIXED64, nil),
+			core.NumericUint64Type,
+		},
+		{field("f_float", 13, descriptorpb.FieldDescriptorProto_TYPE_FLOAT, nil), core.RealType},
+		{
+			field("f_double", 14, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, nil),
+			core.DoublePrecisionType,
+		},
+		{field("f_string", 15, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil), core.TextType},
+		{field("f_bytes", 16, descriptorpb.FieldDescriptorProto_TYPE_BYTES, nil), core.ByteaType},
+		{field("f_message", 17, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, nil), core.ByteaType},
+		{field("group", 18, descriptorpb.FieldDescriptorProto_TYPE_GROUP, nil), core.ByteaType},
+		{
+			field("f_timestamp", 19, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, nil),
+			core.TimestampType,
+		},
+		{field("f_struct", 20, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, nil), core.JSONBType},
+	}
+
+	typeNames := map[string]string{
+		"f_enum":      ".test.Kind",
+		"f_message":   ".test.Kinds.Nested",
+		"group":       ".test.Kinds.Group",
+		"f_timestamp": ".google.protobuf.Timestamp",
+		"f_struct":    ".google.protobuf.Struct",
+	}
+
+	fields := make([]*fd, 0, len(tests))
+	for _, tt := range tests {
+		if name, ok := typeNames[tt.field.GetName()]; ok {
+			tt.field.TypeName = proto.String(name)
+		}
+
+		fields = append(fields, tt.field)
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/kinds.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto2"),
+		Dependency: []string{
+			"google/protobuf/timestamp.proto",
+			"google/protobuf/struct.proto",
+		},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("Kinds"),
+			Field: fields,
+			NestedType: []*descriptorpb.DescriptorProto{
+				{Name: proto.String("Nested")},
+				{Name: proto.String("Group")},
+			},
+		}},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Kind"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+			},
+		}},
+	}
+
+	table := buildSchema(t, file, template.Options{}).Schema.TableByName("Kinds")
+	if table == nil {
+		t.Fatal("table Kinds not built")
+	}
+
+	for _, tt := range tests {
+		column := table.ColumnByName(tt.field.GetName())
+		if column == nil {
+			t.Errorf("column %s not built", tt.field.GetName())
+
+			continue
+		}
+
+		if column.Type != tt.want {
+			t.Errorf("column %s has type %s, want %s", column.Name, column.Type, tt.want)
+		}
+	}
+
+	for _, name := range []string{"f_uint32", "f_fixed32", "f_uint64", "f_fixed64"} {
+		check := name + " >= 0"
+		if !slices.ContainsFunc(table.Constraints, func(c core.Constraint) bool {
+			return c.Type == core.CheckConstraint && c.Expression == check
+		}) {
+			t.Errorf("missing check %q", check)
+		}
+	}
+}
+
+func TestMapWellKnownTypes(t *testing.T) {
+	t.Parallel()
+
+	// google.type is not a dependency of this module, so its messages are
+	// declared with just their names.
+	googleType := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("google/type/types.proto"),
+		Package: proto.String("google.type"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/googletype")},
+	}
+	for _, name := range []string{"Date", "TimeOfDay", "Decimal", "Money", "LatLng"} {
+		googleType.MessageType = append(
+			googleType.MessageType,
+			&descriptorpb.DescriptorProto{Name: proto.String(name)},
+		)
+	}
+
+	tests := []struct {
+		typeName string
+		want     core.ColumnType
+	}{
+		{".google.protobuf.Duration", core.IntervalType},
+		{".google.protobuf.DoubleValue", core.DoublePrecisionType},
+		{".google.protobuf.FloatValue", core.RealType},
+		{".google.protobuf.Int64Value", core.BigIntType},
+		{".google.protobuf.UInt64Value", core.NumericUint64Type},
+		{".google.protobuf.Int32Value", core.IntegerType},
+		{".google.protobuf.UInt32Value", core.BigIntType},
+		{".google.protobuf.BoolValue", core.BooleanType},
+		{".google.protobuf.StringValue", core.TextType},
+		{".google.protobuf.BytesValue", core.ByteaType},
+		{".google.protobuf.Value", core.JSONBType},
+		{".google.protobuf.ListValue", core.JSONBType},
+		{".google.protobuf.Any", core.JSONBType},
+		{".google.type.Date", core.DateType},
+		{".google.type.TimeOfDay", core.TimeType},
+		{".google.type.Decimal", core.NumericType},
+		{".google.type.Money", core.NumericType},
+		{".google.type.LatLng", core.PointType},
+	}
+
+	fields := make([]*descriptorpb.FieldDescriptorProto, 0, len(tests))
+	for i, tt := range tests {
+		f := field(
+			fmt.Sprintf("f%d", i),
+			int32(i+1),
+			descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
+			nil,
+		)
+		f.TypeName = proto.String(tt.typeName)
+		fields = append(fields, f)
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/wkt.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		Dependency: []string{
+			"google/protobuf/any.proto",
+			"google/protobuf/duration.proto",
+			"google/protobuf/struct.proto",
+			"google/protobuf/wrappers.proto",
+			"google/type/types.proto",
+		},
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Wkt"), Field: fields}},
+	}
+
+	table := buildSchema(t, file, template.Options{}, googleType).Schema.TableByName("Wkt")
+	if table == nil {
+		t.Fatal("table Wkt not built")
+	}
+
+	for i, tt := range tests {
+		column := table.ColumnByName(fmt.Sprintf("f%d", i))
+		if column == nil || column.Type != tt.want {
+			t.Errorf("%s: got column %+v, want type %s", tt.typeName, column, tt.want)
+		}
+	}
+
+	moneyColumn := fmt.Sprintf("f%d", len(tests)-2)
+	if currency := table.ColumnByName(moneyColumn + "_currency"); currency == nil ||
+		currency.Type != core.TextType {
+		t.Errorf("money currency column = %+v, want TEXT", currency)
+	}
+}
+
+func TestMapRepeatedTypes(t *testing.T) {
+	t.Parallel()
+
+	repeated := func(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+		return f
+	}
+
+	enum := repeated(field("kinds", 2, descriptorpb.FieldDescriptorProto_TYPE_ENUM, nil))
+	enum.TypeName = proto.String(".test.Kind")
+
+	timestamps := repeated(field("times", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, nil))
+	timestamps.TypeName = proto.String(".google.protobuf.Timestamp")
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/repeated.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		Dependency: []string{
+			"buf/validate/validate.proto",
+			"google/protobuf/timestamp.proto",
+		},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Lists"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				repeated(field("ids", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, nil)),
+				enum,
+				timestamps,
+				repeated(field("refs", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING, validateOpts(
+					validate.FieldRules_builder{Repeated: validate.RepeatedRules_builder{
+						Items: validate.FieldRules_builder{String: validate.StringRules_builder{
+							Uuid: proto.Bool(true),
+						}.Build()}.Build(),
+					}.Build()}.Build(),
+				))),
+				repeated(field("tags", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil)),
+			},
+		}},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Kind"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+			},
+		}},
+	}
+
+	table := buildSchema(t, file, template.Options{}).Schema.TableByName("Lists")
+	if table == nil {
+		t.Fatal("table Lists not built")
+	}
+
+	for name, want := range map[string]core.ColumnType{
+		"ids":   "BIGINT[]",
+		"kinds": `"Kind"[]`,
+		"times": "TIMESTAMPTZ[]",
+		"refs":  "UUID[]",
+		"tags":  core.TextArrayType,
+	} {
+		if column := table.ColumnByName(name); column == nil || column.Type != want {
+			t.Errorf("column %s = %+v, want type %s", name, column, want)
+		}
+	}
+}
+
+// sqlcOpts returns field options carrying sqlc field constraints.
+func sqlcOpts(constraints *sqlcpb.FieldConstraints) *descriptorpb.FieldOptions {
+	opts := &descriptorpb.FieldOptions{}
+	proto.SetExtension(opts, sqlcpb.E_Field, constraints)
+
+	return opts
+}
+
+// mapField returns a map<string, string> field along with its entry message.
+func mapField(
+	name string,
+	number int32,
+	opts *descriptorpb.FieldOptions,
+) (*descriptorpb.FieldDescriptorProto, *descriptorpb.DescriptorProto) {
+	entry := strings.ToUpper(name[:1]) + name[1:] + "Entry"
+
+	f := field(name, number, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, opts)
+	f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	f.TypeName = proto.String(entry)
+
+	return f, &descriptorpb.DescriptorProto{
+		Name: proto.String(entry),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil),
+			field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil),
+		},
+		Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+}
+
+func TestBuildMapFields(t *testing.T) {
+	t.Parallel()
+
+	labels, labelsEntry := mapField("labels", 2, nil)
+	attrs, attrsEntry := mapField("attrs", 3, sqlcOpts(&sqlcpb.FieldConstraints{
+		MapStorage: sqlcpb.MapStorage_MAP_STORAGE_HSTORE,
+	}))
+	notes, notesEntry := mapField("notes", 4, sqlcOpts(&sqlcpb.FieldConstraints{
+		MapStorage: sqlcpb.MapStorage_MAP_STORAGE_TABLE,
+	}))
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/maps.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Book"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+					&sqlcpb.FieldConstraints{Primary: true},
+				)),
+				labels,
+				attrs,
+				notes,
+			},
+			NestedType: []*descriptorpb.DescriptorProto{labelsEntry, attrsEntry, notesEntry},
+		}},
+	}
+
+	sb := buildSchema(t, file, template.Options{})
+
+	book := sb.Schema.TableByName("Book")
+	if book == nil {
+		t.Fatal("table Book not built")
+	}
+
+	for name, want := range map[string]core.ColumnType{
+		"labels": core.JSONBType,
+		"attrs":  core.HStoreType,
+	} {
+		if column := book.ColumnByName(name); column == nil || column.Type != want {
+			t.Errorf("column %s = %+v, want type %s", name, column, want)
+		}
+	}
+
+	if book.ColumnByName("notes") != nil {
+		t.Error("map stored in a child table has a column")
+	}
+
+	if !slices.Contains(sb.Schema.Extensions, "hstore") {
+		t.Error("hstore extension not enabled")
+	}
+
+	notesTable := sb.Schema.TableByName("Book_notes")
+	if notesTable == nil {
+		t.Fatal("table Book_notes not built")
+	}
+
+	if got := notesTable.PrimaryKey(); !slices.Equal(got, []string{"book_id", "key"}) {
+		t.Errorf("Book_notes primary key = %v, want [book_id key]", got)
+	}
+
+	want := []converter.ChildTable{
+		{GoName: "BookNotes", Table: "Book_notes", ParentKey: []string{"book_id"}, Key: "key"},
+	}
+	if got := sb.ChildrenByMessage["test.Book"]; len(got) != 1 || got[0].GoName != want[0].GoName ||
+		got[0].Table != want[0].Table || !slices.Equal(got[0].ParentKey, want[0].ParentKey) {
+		t.Errorf("children = %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildEmbeddedFields(t *testing.T) {
+	t.Parallel()
+
+	embedded := func(name string, number int32, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := field(name, number, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, sqlcOpts(
+			&sqlcpb.FieldConstraints{Embed: true},
+		))
+		f.TypeName = proto.String(typeName)
+
+		return f
+	}
+
+	street := field("street", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, validateOpts(
+		validate.FieldRules_builder{
+			Required: proto.Bool(true),
+			String:   validate.StringRules_builder{MinLen: proto.Uint64(1)}.Build(),
+		}.Build(),
+	))
+	zip := field("zip", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, sqlcOpts(
+		&sqlcpb.FieldConstraints{Index: &sqlcpb.Index{}},
+	))
+
+	billing := embedded("billing", 4, ".test.Address")
+	proto.SetExtension(billing.Options, validate.E_Field, validate.FieldRules_builder{
+		Required: proto.Bool(true),
+	}.Build())
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/embed.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"buf/validate/validate.proto", "sqlc/sqlc.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Order"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+						&sqlcpb.FieldConstraints{Primary: true},
+					)),
+					embedded("shipping", 2, ".test.Address"),
+					embedded("loop", 3, ".test.Loop"),
+					billing,
+				},
+			},
+			{
+				Name:  proto.String("Address"),
+				Field: []*descriptorpb.FieldDescriptorProto{street, zip},
+			},
+			{
+				Name: proto.String("Loop"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("x", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, nil),
+					embedded("next", 2, ".test.Loop"),
+				},
+			},
+		},
+	}
+
+	sb := buildSchema(t, file, template.Options{})
+
+	order := sb.Schema.TableByName("Order")
+	if order == nil {
+		t.Fatal("table Order not built")
+	}
+
+	columns := make([]string, 0, len(order.Columns))
+	for _, column := range order.Columns {
+		columns = append(columns, column.Name)
+	}
+
+	want := []string{"id", "shipping_street", "shipping_zip", "loop_x", "billing_street", "billing_zip"}
+	if !slices.Equal(columns, want) {
+		t.Errorf("columns = %v, want %v", columns, want)
+	}
+
+	// Required fields are only NOT NULL when the message embedding them is
+	// always set
+	if column := order.ColumnByName("shipping_street"); column == nil || column.NotNull {
+		t.Errorf("column shipping_street = %+v, want nullable", column)
+	}
+
+	if column := order.ColumnByName("billing_street"); column == nil || !column.NotNull {
+		t.Errorf("column billing_street = %+v, want NOT NULL", column)
+	}
+
+	if !slices.ContainsFunc(order.Constraints, func(c core.Constraint) bool {
+		return c.Type == core.CheckConstraint && c.Name == "Order_shipping_street_check" &&
+			c.Expression == "char_length(shipping_street) >= 1"
+	}) {
+		t.Errorf("check on shipping_street not built: %+v", order.Constraints)
+	}
+
+	if !slices.ContainsFunc(order.Indexes, func(i core.Index) bool {
+		return slices.Equal(i.Columns, []string{"shipping_zip"})
+	}) {
+		t.Errorf("index on shipping_zip not built: %+v", order.Indexes)
+	}
+
+	childTable := func(name string, number int32, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := field(name, number, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, sqlcOpts(
+			&sqlcpb.FieldConstraints{ChildTable: true},
+		))
+		f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		f.TypeName = proto.String(typeName)
+
+		return f
+	}
+
+	// Child tables are only built for fields of the table message itself
+	nested := proto.CloneOf(file)
+	nested.MessageType[1].Field = append(nested.MessageType[1].Field, childTable("notes", 3, ".test.Loop"))
+
+	err := converter.NewSchemaBuilder(template.Options{}).Build(newPlugin(t, nested))
+	if !errors.Is(err, converter.ErrNestedChildTable) {
+		t.Errorf("embedded child table error = %v, want ErrNestedChildTable", err)
+	}
+
+	nested = proto.CloneOf(file)
+	nested.MessageType[0].Field = append(nested.MessageType[0].Field, childTable("stops", 5, ".test.Stop"))
+	nested.MessageType = append(nested.MessageType, &descriptorpb.DescriptorProto{
+		Name:  proto.String("Stop"),
+		Field: []*descriptorpb.FieldDescriptorProto{childTable("legs", 1, ".test.Address")},
+	})
+
+	err = converter.NewSchemaBuilder(template.Options{}).Build(newPlugin(t, nested))
+	if !errors.Is(err, converter.ErrNestedChildTable) {
+		t.Errorf("element child table error = %v, want ErrNestedChildTable", err)
+	}
+}
+
+func TestBuildRepeatedMessageTable(t *testing.T) {
+	t.Parallel()
+
+	chapters := field("chapters", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, sqlcOpts(
+		&sqlcpb.FieldConstraints{ChildTable: true},
+	))
+	chapters.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	chapters.TypeName = proto.String(".test.Chapter")
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/chapters.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Book"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+						&sqlcpb.FieldConstraints{Primary: true},
+					)),
+					chapters,
+				},
+			},
+			{
+				Name: proto.String("Chapter"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, nil),
+					field("title", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil),
+					field("pages", 3, descriptorpb.FieldDescriptorProto_TYPE_UINT32, nil),
+				},
+			},
+		},
+	}
+
+	sb := buildSchema(t, file, template.Options{})
+
+	if book := sb.Schema.TableByName("Book"); book == nil || book.ColumnByName("chapters") != nil {
+		t.Errorf("table Book = %+v, want no chapters column", book)
+	}
+
+	names := make([]string, 0, len(sb.Schema.Tables))
+	for _, table := range sb.Schema.Tables {
+		names = append(names, table.Name)
+	}
+
+	if want := []string{"Book", "Book_chapters", "Chapter"}; !slices.Equal(names, want) {
+		t.Errorf("tables = %v, want %v", names, want)
+	}
+
+	table := sb.Schema.TableByName("Book_chapters")
+	if table == nil {
+		t.Fatal("table Book_chapters not built")
+	}
+
+	columns := make([]string, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		columns = append(columns, column.Name)
+	}
+
+	// The parent key is prefixed, so it does not clash with the id of a chapter
+	if want := []string{"book_id", "ordinal", "id", "title", "pages"}; !slices.Equal(columns, want) {
+		t.Errorf("columns = %v, want %v", columns, want)
+	}
+
+	if got := table.PrimaryKey(); !slices.Equal(got, []string{"book_id", "ordinal"}) {
+		t.Errorf("primary key = %v, want [book_id ordinal]", got)
+	}
+
+	if !slices.ContainsFunc(table.Constraints, func(c core.Constraint) bool {
+		return c.Type == core.ForeignKeyConstraint && c.References.Table == "Book" &&
+			slices.Equal(c.Columns, []string{"book_id"}) &&
+			slices.Equal(c.References.Columns, []string{"id"})
+	}) {
+		t.Errorf("foreign key to Book not built: %+v", table.Constraints)
+	}
+
+	if !slices.ContainsFunc(table.Constraints, func(c core.Constraint) bool {
+		return c.Type == core.CheckConstraint && c.Expression == "pages >= 0"
+	}) {
+		t.Errorf("check on pages not built: %+v", table.Constraints)
+	}
+
+	children := sb.ChildrenByMessage["test.Book"]
+	if len(children) != 1 || children[0].GoName != "BookChapters" || children[0].Key != "ordinal" ||
+		!slices.Equal(children[0].ParentKey, []string{"book_id"}) {
+		t.Errorf("children = %+v, want BookChapters keyed by book_id and ordinal", children)
+	}
+
+	// An element field named like the prefixed parent key still clashes
+	clash := proto.CloneOf(file)
+	clash.MessageType[1].Field[0].Name = proto.String("book_id")
+	clash.MessageType[1].Field[0].JsonName = proto.String("bookId")
+
+	err := converter.NewSchemaBuilder(template.Options{}).Build(newPlugin(t, clash))
+	if err == nil || !strings.Contains(err.Error(), "clashes with a column added by the child table") {
+		t.Errorf("Build error = %v, want a clash with the parent key", err)
+	}
+}
+
+func TestBuildOneofs(t *testing.T) {
+	t.Parallel()
+
+	member := func(
+		name string,
+		number int32,
+		oneof int32,
+		opts *descriptorpb.FieldOptions,
+	) *descriptorpb.FieldDescriptorProto {
+		f := field(name, number, descriptorpb.FieldDescriptorProto_TYPE_STRING, opts)
+		f.OneofIndex = proto.Int32(oneof)
+
+		return f
+	}
+
+	note := member("note", 6, 2, nil)
+	note.Proto3Optional = proto.Bool(true)
+
+	required := &descriptorpb.OneofOptions{}
+	proto.SetExtension(required, validate.E_Oneof, validate.OneofRules_builder{
+		Required: proto.Bool(true),
+	}.Build())
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/oneofs.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"buf/validate/validate.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Contact"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				member("email", 1, 0, validateOpts(validate.FieldRules_builder{
+					Required: proto.Bool(true),
+				}.Build())),
+				member("phone", 2, 0, nil),
+				member("nickname", 3, 1, nil),
+				member("handle", 4, 1, nil),
+				note,
+			},
+			OneofDecl: []*descriptorpb.OneofDescriptorProto{
+				{Name: proto.String("channel"), Options: required},
+				{Name: proto.String("alias")},
+				{Name: proto.String("_note")},
+			},
+		}},
+	}
+
+	sb := buildSchema(t, file, template.Options{})
+
+	contact := sb.Schema.TableByName("Contact")
+	if contact == nil {
+		t.Fatal("table Contact not built")
+	}
+
+	if column := contact.ColumnByName("email"); column == nil || column.NotNull {
+		t.Errorf("column email = %+v, want nullable", column)
+	}
+
+	var checks []string
+
+	for _, constraint := range contact.Constraints {
+		if constraint.Type == core.CheckConstraint {
+			checks = append(checks, constraint.Name+": "+constraint.Expression)
+		}
+	}
+
+	want := []string{
+		"Contact_channel_check: num_nonnulls(email, phone) = 1",
+		"Contact_alias_check: num_nonnulls(nickname, handle) <= 1",
+	}
+	if !slices.Equal(checks, want) {
+		t.Errorf("checks = %v, want %v", checks, want)
+	}
+
+	// Members renamed to snake_case are still grouped by oneof
+	camel := proto.CloneOf(file)
+	camel.MessageType[0].Field[0].Name = proto.String("emailAddress")
+	camel.MessageType[0].Field[1].Name = proto.String("phoneNumber")
+	camel.MessageType[0].OneofDecl[0].Name = proto.String("contactChannel")
+
+	contact = buildSchema(t, camel, template.Options{Naming: core.NamingSnakeCase}).
+		Schema.TableByName("contact")
+	if contact == nil {
+		t.Fatal("table contact not built")
+	}
+
+	if !slices.ContainsFunc(contact.Constraints, func(c core.Constraint) bool {
+		return c.Name == "contact_contact_channel_check" &&
+			c.Expression == "num_nonnulls(email_address, phone_number) = 1"
+	}) {
+		t.Errorf("check on snake_case oneof not built: %+v", contact.Constraints)
+	}
+}
+
+func TestBuildOneofEmbeddedPresence(t *testing.T) {
+	t.Parallel()
+
+	card := field("card", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, sqlcOpts(
+		&sqlcpb.FieldConstraints{Embed: true},
+	))
+	card.TypeName = proto.String(".test.Card")
+	card.OneofIndex = proto.Int32(0)
+
+	iban := field("iban", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil)
+	iban.OneofIndex = proto.Int32(0)
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/payment.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Payment"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					card,
+					iban,
+					field("amount", 3, descriptorpb.FieldDescriptorProto_TYPE_INT64, nil),
+				},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("method")}},
+			},
+			{
+				Name: proto.String("Card"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("number", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil),
+					field("cvv", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, nil),
+				},
+			},
+		},
+	}
+
+	payment := buildSchema(t, file, template.Options{FieldPresence: true}).Schema.TableByName("Payment")
+	if payment == nil {
+		t.Fatal("table Payment not built")
+	}
+
+	// Members flattened through the oneof stay nullable despite their implicit
+	// presence, while other fields follow it
+	for _, tt := range []struct {
+		column  string
+		notNull bool
+		def     string
+	}{
+		{column: "card_number"},
+		{column: "card_cvv"},
+		{column: "iban"},
+		{column: "amount", notNull: true, def: "0"},
+	} {
+		column := payment.ColumnByName(tt.column)
+		if column == nil || column.NotNull != tt.notNull || column.DefaultValue != tt.def {
+			t.Errorf("column %s = %+v, want NotNull %t DEFAULT %q", tt.column, column, tt.notNull, tt.def)
+		}
+	}
+}
+
+func TestBuildFieldPresence(t *testing.T) {
+	t.Parallel()
+
+	note := field("note", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil)
+	note.OneofIndex = proto.Int32(0)
+	note.Proto3Optional = proto.Bool(true)
+
+	kind := field("kind", 5, descriptorpb.FieldDescriptorProto_TYPE_ENUM, nil)
+	kind.TypeName = proto.String(".test.Kind")
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/presence.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Kind"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("KIND_BOOK"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Item"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+					&sqlcpb.FieldConstraints{Primary: true},
+				)),
+				field("name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, nil),
+				note,
+				field("active", 4, descriptorpb.FieldDescriptorProto_TYPE_BOOL, nil),
+				kind,
+				field("stock", 6, descriptorpb.FieldDescriptorProto_TYPE_INT32, sqlcOpts(
+					&sqlcpb.FieldConstraints{Default: "1"},
+				)),
+			},
+			OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("_note")}},
+		}},
+	}
+
+	tests := []struct {
+		column  string
+		notNull bool
+		def     string
+	}{
+		{column: "id", notNull: true},
+		{column: "name", notNull: true, def: "''"},
+		{column: "note"},
+		{column: "active", notNull: true, def: "false"},
+		{column: "kind", notNull: true, def: "'KIND_UNSPECIFIED'"},
+		{column: "stock", notNull: true, def: "1"},
+	}
+
+	item := buildSchema(t, file, template.Options{FieldPresence: true}).Schema.TableByName("Item")
+	if item == nil {
+		t.Fatal("table Item not built")
+	}
+
+	for _, tt := range tests {
+		column := item.ColumnByName(tt.column)
+		if column == nil {
+			t.Errorf("column %s not built", tt.column)
+
+			continue
+		}
+
+		if column.NotNull != tt.notNull || column.DefaultValue != tt.def {
+			t.Errorf("column %s = %+v, want NOT NULL %v DEFAULT %q",
+				tt.column, column, tt.notNull, tt.def)
+		}
+	}
+
+	plain := buildSchema(t, file, template.Options{}).Schema.TableByName("Item")
+	if column := plain.ColumnByName("name"); column == nil || column.NotNull {
+		t.Errorf("column name = %+v, want nullable without field_presence", column)
+	}
+}
+
+func TestBuildProto2Fields(t *testing.T) {
+	t.Parallel()
+
+	proto2Field := func(
+		name string,
+		number int32,
+		typ descriptorpb.FieldDescriptorProto_Type,
+		label descriptorpb.FieldDescriptorProto_Label,
+		def string,
+	) *descriptorpb.FieldDescriptorProto {
+		f := field(name, number, typ, nil)
+		f.Label = label.Enum()
+
+		if def != "" {
+			f.DefaultValue = proto.String(def)
+		}
+
+		return f
+	}
+
+	kind := proto2Field("kind", 4, descriptorpb.FieldDescriptorProto_TYPE_ENUM,
+		descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, "KIND_BOOK")
+	kind.TypeName = proto.String(".test.Kind")
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/proto2.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto2"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Kind"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("KIND_BOOK"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Item"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				proto2Field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING,
+					descriptorpb.FieldDescriptorProto_LABEL_REQUIRED, ""),
+				proto2Field("title", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING,
+					descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, "it's"),
+				proto2Field("ratio", 3, descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+					descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, "0.1"),
+				kind,
+				proto2Field("stock", 5, descriptorpb.FieldDescriptorProto_TYPE_INT32,
+					descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, ""),
+			},
+		}},
+	}
+
+	tests := []struct {
+		column  string
+		notNull bool
+		def     string
+	}{
+		{column: "name", notNull: true},
+		{column: "title", def: "'it''s'"},
+		{column: "ratio", def: "0.1"},
+		{column: "kind", def: "'KIND_BOOK'"},
+		{column: "stock"},
+	}
+
+	// Proto2 optional fields track presence, so field_presence keeps them
+	// nullable
+	item := buildSchema(t, file, template.Options{FieldPresence: true}).Schema.TableByName("Item")
+	if item == nil {
+		t.Fatal("table Item not built")
+	}
+
+	for _, tt := range tests {
+		column := item.ColumnByName(tt.column)
+		if column == nil {
+			t.Errorf("column %s not built", tt.column)
+
+			continue
+		}
+
+		if column.NotNull != tt.notNull || column.DefaultValue != tt.def {
+			t.Errorf("column %s = %+v, want NOT NULL %v DEFAULT %q",
+				tt.column, column, tt.notNull, tt.def)
+		}
+	}
+
+	// Closed enums only hold defined values, which the native type enforces
+	// and a check does for text storage
+	isKindCheck := func(c core.Constraint) bool {
+		return c.Type == core.CheckConstraint &&
+			c.Expression == "kind IN ('KIND_UNSPECIFIED', 'KIND_BOOK')"
+	}
+
+	if slices.ContainsFunc(item.Constraints, isKindCheck) {
+		t.Errorf("redundant check on native enum kind built: %+v", item.Constraints)
+	}
+
+	item = buildSchema(t, file, template.Options{EnumStorage: core.EnumStorageText}).Schema.TableByName("Item")
+	if item == nil || !slices.ContainsFunc(item.Constraints, isKindCheck) {
+		t.Errorf("check on closed enum kind not built: %+v", item)
+	}
+}
+
+func TestBuildEnumStorage(t *testing.T) {
+	t.Parallel()
+
+	enum := func(name string, storage sqlcpb.EnumStorage) *descriptorpb.EnumDescriptorProto {
+		opts := &descriptorpb.EnumOptions{}
+		if storage != sqlcpb.EnumStorage_ENUM_STORAGE_UNSPECIFIED {
+			proto.SetExtension(opts, sqlcpb.E_Enum, &sqlcpb.EnumOptions{Storage: storage})
+		}
+
+		prefix := strings.ToUpper(name) + "_"
+
+		return &descriptorpb.EnumDescriptorProto{
+			Name: proto.String(name),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String(prefix + "UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String(prefix + "BIG"), Number: proto.Int32(1)},
+			},
+			Options: opts,
+		}
+	}
+
+	enumField := func(name string, number int32, def string) *descriptorpb.FieldDescriptorProto {
+		var opts *descriptorpb.FieldOptions
+		if def != "" {
+			opts = sqlcOpts(&sqlcpb.FieldConstraints{Default: def})
+		}
+
+		f := field(strings.ToLower(name), number, descriptorpb.FieldDescriptorProto_TYPE_ENUM, opts)
+		f.TypeName = proto.String(".test." + name)
+
+		return f
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/enums.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			enum("Kind", sqlcpb.EnumStorage_ENUM_STORAGE_UNSPECIFIED),
+			enum("Color", sqlcpb.EnumStorage_ENUM_STORAGE_SMALLINT),
+			enum("Size", sqlcpb.EnumStorage_ENUM_STORAGE_TABLE),
+			enum("Shape", sqlcpb.EnumStorage_ENUM_STORAGE_NATIVE),
+		},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Item"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				enumField("Kind", 1, "KIND_BIG"),
+				enumField("Color", 2, "COLOR_BIG"),
+				enumField("Size", 3, ""),
+				enumField("Shape", 4, ""),
+			},
+		}},
+	}
+
+	sb := buildSchema(t, file, template.Options{EnumStorage: core.EnumStorageText})
+
+	storages := make(map[string]core.EnumStorage)
+	for _, e := range sb.Schema.Enums {
+		storages[e.Name] = e.Storage
+	}
+
+	wantStorages := map[string]core.EnumStorage{
+		"Kind":  core.EnumStorageText,
+		"Color": core.EnumStorageSmallInt,
+		"Size":  core.EnumStorageTable,
+		"Shape": core.EnumStorageNative,
+	}
+	if !maps.Equal(storages, wantStorages) {
+		t.Errorf("enum storages = %v, want %v", storages, wantStorages)
+	}
+
+	item := sb.Schema.TableByName("Item")
+	if item == nil {
+		t.Fatal("table Item not built")
+	}
+
+	for _, tt := range []struct {
+		column string
+		typ    core.ColumnType
+		def    string
+	}{
+		{column: "kind", typ: core.TextType, def: "'KIND_BIG'"},
+		{column: "color", typ: core.SmallIntType, def: "1"},
+		{column: "size", typ: core.TextType},
+		{column: "shape", typ: `"Shape"`},
+	} {
+		column := item.ColumnByName(tt.column)
+		if column == nil || column.Type != tt.typ || column.DefaultValue != tt.def {
+			t.Errorf("column %s = %+v, want type %s DEFAULT %q", tt.column, column, tt.typ, tt.def)
+		}
+	}
+
+	constraints := func(item *core.Table) []string {
+		var checks []string
+
+		for _, constraint := range item.Constraints {
+			switch constraint.Type {
+			case core.CheckConstraint:
+				checks = append(checks, constraint.Expression)
+			case core.ForeignKeyConstraint:
+				checks = append(checks, "FK "+constraint.Columns[0]+" "+constraint.References.Table)
+			}
+		}
+
+		return checks
+	}
+
+	// Text columns only hold value names, while open enums may store unknown
+	// numbers in smallint columns
+	want := []string{"kind IN ('KIND_UNSPECIFIED', 'KIND_BIG')", "FK size Size"}
+	if got := constraints(item); !slices.Equal(got, want) {
+		t.Errorf("constraints = %v, want %v", got, want)
+	}
+
+	closed := proto.CloneOf(file)
+	closed.Syntax = proto.String("proto2")
+
+	item = buildSchema(t, closed, template.Options{EnumStorage: core.EnumStorageText}).
+		Schema.TableByName("Item")
+
+	want = []string{"kind IN ('KIND_UNSPECIFIED', 'KIND_BIG')", "color IN (0, 1)", "FK size Size"}
+	if got := constraints(item); !slices.Equal(got, want) {
+		t.Errorf("closed enum constraints = %v, want %v", got, want)
+	}
+}
+
+func TestBuildMySQL(t *testing.T) {
+	t.Parallel()
+
+	kind := field("kind", 3, descriptorpb.FieldDescriptorProto_TYPE_ENUM, nil)
+	kind.TypeName = proto.String(".test.Kind")
+
+	tags := field("tags", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING, validateOpts(
+		validate.FieldRules_builder{Repeated: validate.RepeatedRules_builder{
+			MinItems: proto.Uint64(1),
+		}.Build()}.Build(),
+	))
+	tags.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	proto.SetExtension(tags.Options, sqlcpb.E_Field, &sqlcpb.FieldConstraints{
+		Default: "{}",
+		Index:   &sqlcpb.Index{},
+	})
+
+	title := field("title", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING, validateOpts(
+		validate.FieldRules_builder{String: validate.StringRules_builder{
+			MaxLen: proto.Uint64(200),
+		}.Build()}.Build(),
+	))
+	proto.SetExtension(title.Options, sqlcpb.E_Field, &sqlcpb.FieldConstraints{
+		Index: &sqlcpb.Index{
+			Method: sqlcpb.IndexMethod_INDEX_METHOD_HASH,
+			Where:  "title <> ''",
+		},
+	})
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/mysql.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"buf/validate/validate.proto", "sqlc/sqlc.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Kind"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("KIND_BIG"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Author"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+						&sqlcpb.FieldConstraints{Primary: true},
+					)),
+				},
+			},
+			{
+				Name: proto.String("Book"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("author_id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+						&sqlcpb.FieldConstraints{References: "Author.id", Deferrable: true},
+					)),
+					field("isbn", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, validateOpts(
+						validate.FieldRules_builder{String: validate.StringRules_builder{
+							Pattern: proto.String(`^\d+X?$`),
+						}.Build()}.Build(),
+					)),
+					kind,
+					tags,
+					title,
+					field("cover", 6, descriptorpb.FieldDescriptorProto_TYPE_BYTES, nil),
+				},
+			},
+		},
+	}
+
+	sb := buildSchema(t, file, template.Options{Dialect: core.MySQL{}, FieldPresence: true})
+
+	if len(sb.Schema.Enums) != 1 {
+		t.Errorf("enums = %+v, want Kind", sb.Schema.Enums)
+	}
+
+	book := sb.Schema.TableByName("Book")
+	if book == nil {
+		t.Fatal("table Book not built")
+	}
+
+	if column := book.ColumnByName("kind"); column == nil ||
+		column.Type != "ENUM('KIND_UNSPECIFIED', 'KIND_BIG')" {
+		t.Errorf("column kind = %+v, want an inline ENUM type", column)
+	}
+
+	var constraints []string
+
+	for _, constraint := range book.Constraints {
+		switch constraint.Type {
+		case core.CheckConstraint:
+			constraints = append(constraints, constraint.Expression)
+		case core.ForeignKeyConstraint:
+			constraints = append(constraints, fmt.Sprintf(
+				"FK %s deferrable=%t", constraint.Columns[0], constraint.References.Deferrable,
+			))
+		}
+	}
+
+	want := []string{
+		"FK author_id deferrable=false",
+		`isbn REGEXP '^\\d+X?$'`,
+		"JSON_LENGTH(tags) >= 1",
+		"char_length(title) <= 200",
+	}
+	if !slices.Equal(constraints, want) {
+		t.Errorf("constraints = %v, want %v", constraints, want)
+	}
+
+	// Strings are only bounded by max_len, TEXT and BLOB defaults are
+	// expressions and JSON columns take no default
+	for _, want := range []core.Column{
+		{Name: "isbn", Type: core.TextType, NotNull: true, DefaultValue: "('')"},
+		{Name: "title", Type: "VARCHAR(200)", NotNull: true, DefaultValue: "''"},
+		{Name: "tags", Type: core.TextArrayType},
+		{Name: "cover", Type: core.ByteaType, NotNull: true, DefaultValue: "(X'')"},
+	} {
+		if got := book.ColumnByName(want.Name); got == nil || *got != want {
+			t.Errorf("column %s = %+v, want %+v", want.Name, got, want)
+		}
+	}
+
+	// The index on the JSON column is dropped
+	if len(book.Indexes) != 1 || !slices.Equal(book.Indexes[0].Columns, []string{"title"}) ||
+		book.Indexes[0].Method != "" || book.Indexes[0].Where != "" {
+		t.Errorf("indexes = %+v, want a plain index on title", book.Indexes)
+	}
+
+	// Keys cannot be dropped like indexes
+	isbn := file.GetMessageType()[1].GetField()[1]
+	proto.SetExtension(isbn.Options, sqlcpb.E_Field, &sqlcpb.FieldConstraints{Unique: true})
+
+	err := converter.NewSchemaBuilder(template.Options{Dialect: core.MySQL{}}).
+		Build(newPlugin(t, file))
+	if !errors.Is(err, converter.ErrUnindexableKey) {
+		t.Errorf("unique LONGTEXT column error = %v, want ErrUnindexableKey", err)
+	}
+}
+
+func TestBuildEnumValueNames(t *testing.T) {
+	t.Parallel()
+
+	enumOpts := &descriptorpb.EnumOptions{}
+	proto.SetExtension(enumOpts, sqlcpb.E_Enum, &sqlcpb.EnumOptions{
+		StripPrefix:     true,
+		Lowercase:       true,
+		OmitUnspecified: true,
+	})
+
+	renamed := &descriptorpb.EnumValueOptions{}
+	proto.SetExtension(renamed, sqlcpb.E_EnumValue, &sqlcpb.EnumValueOptions{Name: "nonfiction"})
+
+	bookType := field("type", 2, descriptorpb.FieldDescriptorProto_TYPE_ENUM, sqlcOpts(
+		&sqlcpb.FieldConstraints{Default: "BOOK_TYPE_FICTION"},
+	))
+	bookType.TypeName = proto.String(".test.BookType")
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/names.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("BookType"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("BOOK_TYPE_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("BOOK_TYPE_FICTION"), Number: proto.Int32(1)},
+				{
+					Name:    proto.String("BOOK_TYPE_NON_FICTION"),
+					Number:  proto.Int32(2),
+					Options: renamed,
+				},
+			},
+			Options: enumOpts,
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Book"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+					&sqlcpb.FieldConstraints{Primary: true},
+				)),
+				bookType,
+			},
+		}},
+	}
+
+	sb := buildSchema(t, file, template.Options{FieldPresence: true})
+
+	if len(sb.Schema.Enums) != 1 {
+		t.Fatalf("enums = %+v, want BookType", sb.Schema.Enums)
+	}
+
+	want := []core.EnumValue{{Name: "fiction", Number: 1}, {Name: "nonfiction", Number: 2}}
+	if got := sb.Schema.Enums[0].Values; !slices.Equal(got, want) {
+		t.Errorf("values = %+v, want %+v", got, want)
+	}
+
+	// The unspecified value is stored as NULL, so the column stays nullable
+	column := sb.Schema.TableByName("Book").ColumnByName("type")
+	if column == nil || column.NotNull || column.DefaultValue != "'fiction'" {
+		t.Errorf("column type = %+v, want nullable with DEFAULT 'fiction'", column)
+	}
+}
+
+func TestBuildNestedEnums(t *testing.T) {
+	t.Parallel()
+
+	enum := func(name string) *descriptorpb.EnumDescriptorProto {
+		return &descriptorpb.EnumDescriptorProto{
+			Name: proto.String(name),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("FORMAT_UNSPECIFIED"), Number: proto.Int32(0)},
+			},
+		}
+	}
+
+	t.Run("nested", func(t *testing.T) {
+		t.Parallel()
+
+		format := field("format", 1, descriptorpb.FieldDescriptorProto_TYPE_ENUM, nil)
+		format.TypeName = proto.String(".test.Book.Format")
+
+		file := &descriptorpb.FileDescriptorProto{
+			Name:    proto.String("test/nested.proto"),
+			Package: proto.String("test"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name:     proto.String("Book"),
+				Field:    []*descriptorpb.FieldDescriptorProto{format},
+				EnumType: []*descriptorpb.EnumDescriptorProto{enum("Format")},
+				NestedType: []*descriptorpb.DescriptorProto{{
+					Name:     proto.String("Edition"),
+					EnumType: []*descriptorpb.EnumDescriptorProto{enum("Format")},
+				}},
+			}},
+		}
+
+		sb := buildSchema(t, file, template.Options{})
+
+		names := make([]string, 0, len(sb.Schema.Enums))
+		for _, e := range sb.Schema.Enums {
+			names = append(names, e.Name)
+		}
+
+		if want := []string{"Book_Edition_Format", "Book_Format"}; !slices.Equal(names, want) {
+			t.Errorf("enums = %v, want %v", names, want)
+		}
+
+		column := sb.Schema.TableByName("Book").ColumnByName("format")
+		if column == nil || column.Type != `"Book_Format"` {
+			t.Errorf("column format = %+v, want type \"Book_Format\"", column)
+		}
+	})
+
+	t.Run("enum only file", func(t *testing.T) {
+		t.Parallel()
+
+		file := &descriptorpb.FileDescriptorProto{
+			Name:     proto.String("test/enums.proto"),
+			Package:  proto.String("test"),
+			Syntax:   proto.String("proto3"),
+			EnumType: []*descriptorpb.EnumDescriptorProto{enum("Format")},
+		}
+
+		sb := buildSchema(t, file, template.Options{})
+
+		if len(sb.Schema.Enums) != 1 || sb.Schema.Enums[0].Name != "Format" {
+			t.Errorf("enums = %+v, want Format", sb.Schema.Enums)
+		}
+	})
+}
+
+func TestBuildNestedMessages(t *testing.T) {
+	t.Parallel()
+
+	tags, tagsEntry := mapField("tags", 2, nil)
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/library.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Library"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, nil),
+				tags,
+			},
+			NestedType: []*descriptorpb.DescriptorProto{
+				tagsEntry,
+				{
+					Name: proto.String("BookShelf"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, nil),
+					},
+				},
+			},
+		}},
+	}
+
+	tables := func(sb *converter.SchemaBuilder) []string {
+		names := make([]string, 0, len(sb.Schema.Tables))
+		for _, table := range sb.Schema.Tables {
+			names = append(names, table.Name)
+		}
+
+		return names
+	}
+
+	if got := tables(buildSchema(t, file, template.Options{})); !slices.Equal(got, []string{"Library"}) {
+		t.Errorf("tables = %v, want [Library]", got)
+	}
+
+	// Nested tables are qualified like nested enums, keeping their case
+	sb := buildSchema(t, file, template.Options{NestedMessages: true})
+
+	if got, want := tables(sb), []string{"Library", "Library_BookShelf"}; !slices.Equal(got, want) {
+		t.Errorf("tables = %v, want %v", got, want)
+	}
+
+	if got := sb.TablesByMessage["test.Library.BookShelf"]; got != "Library_BookShelf" {
+		t.Errorf("table of Library.BookShelf = %q, want Library_BookShelf", got)
+	}
+
+	if got := sb.QueryNames["test.Library.BookShelf"]; got != "LibraryBookShelf" {
+		t.Errorf("query name of Library.BookShelf = %q, want LibraryBookShelf", got)
+	}
+
+	sb = buildSchema(t, file, template.Options{
+		NestedMessages: true,
+		Naming:         core.NamingSnakeCase,
+	})
+
+	if got, want := tables(sb), []string{"library", "library_book_shelf"}; !slices.Equal(got, want) {
+		t.Errorf("snake_case tables = %v, want %v", got, want)
+	}
+
+	// A top-level LibraryBookShelf would get the queries of Library.BookShelf
+	clash := proto.CloneOf(file)
+	clash.MessageType = append(clash.MessageType, &descriptorpb.DescriptorProto{
+		Name: proto.String("LibraryBookShelf"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, nil),
+		},
+	})
+
+	err := converter.NewSchemaBuilder(template.Options{NestedMessages: true}).
+		Build(newPlugin(t, clash))
+	if !errors.Is(err, converter.ErrDuplicateName) {
+		t.Errorf("clashing query names error = %v, want ErrDuplicateName", err)
+	}
+}
+
+func TestBuildDuplicateNames(t *testing.T) {
+	t.Parallel()
+
+	renamed := &descriptorpb.MessageOptions{}
+	proto.SetExtension(renamed, sqlcpb.E_Table, &sqlcpb.TableOptions{Name: "Book"})
+
+	message := func(name string, opts *descriptorpb.MessageOptions) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{
+			Name: proto.String(name),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, nil),
+			},
+			Options: opts,
+		}
+	}
+
+	book := message("Book", nil)
+	book.EnumType = []*descriptorpb.EnumDescriptorProto{{
+		Name: proto.String("Format"),
+		Value: []*descriptorpb.EnumValueDescriptorProto{
+			{Name: proto.String("FORMAT_UNSPECIFIED"), Number: proto.Int32(0)},
+		},
+	}}
+
+	for _, tt := range []struct {
+		name     string
+		messages []*descriptorpb.DescriptorProto
+		opts     template.Options
+		wantErr  bool
+	}{
+		{"tables", []*descriptorpb.DescriptorProto{message("Book", nil), message("Novel", renamed)},
+			template.Options{}, true},
+		{"table and enum type", []*descriptorpb.DescriptorProto{book, message("BookFormat", nil)},
+			template.Options{Naming: core.NamingSnakeCase}, true},
+		{"table and lookup table", []*descriptorpb.DescriptorProto{book, message("BookFormat", nil)},
+			template.Options{Naming: core.NamingSnakeCase, EnumStorage: core.EnumStorageTable}, true},
+		// Text enums and MySQL inline enums create no type
+		{"table and text enum", []*descriptorpb.DescriptorProto{book, message("BookFormat", nil)},
+			template.Options{Naming: core.NamingSnakeCase, EnumStorage: core.EnumStorageText}, false},
+		{"table and inline enum", []*descriptorpb.DescriptorProto{book, message("BookFormat", nil)},
+			template.Options{Naming: core.NamingSnakeCase, Dialect: core.MySQL{}}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			file := &descriptorpb.FileDescriptorProto{
+				Name:        proto.String("test/names.proto"),
+				Package:     proto.String("test"),
+				Syntax:      proto.String("proto3"),
+				Dependency:  []string{"sqlc/sqlc.proto"},
+				MessageType: tt.messages,
+			}
+
+			err := converter.NewSchemaBuilder(tt.opts).Build(newPlugin(t, file))
+			if got := errors.Is(err, converter.ErrDuplicateName); got != tt.wantErr {
+				t.Errorf("error = %v, want ErrDuplicateName: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNamingStrategy(t *testing.T) {
+	t.Parallel()
+
+	kind := field("kind", 3, descriptorpb.FieldDescriptorProto_TYPE_ENUM, nil)
+	kind.TypeName = proto.String(".test.BookKind")
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/naming.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("BookKind"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("BOOK_KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("LibraryBranch"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+						&sqlcpb.FieldConstraints{Primary: true},
+					)),
+				},
+			},
+			{
+				Name: proto.String("Book"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("bookId", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+						&sqlcpb.FieldConstraints{Primary: true},
+					)),
+					field("branchId", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+						&sqlcpb.FieldConstraints{
+							References: "LibraryBranch.id",
+							Index:      &sqlcpb.Index{},
+						},
+					)),
+					kind,
+				},
+			},
+			{
+				Name: proto.String("Shelf"),
+				Options: func() *descriptorpb.MessageOptions {
+					opts := &descriptorpb.MessageOptions{}
+					proto.SetExtension(opts, sqlcpb.E_Table, &sqlcpb.TableOptions{Name: "Shelf"})
+
+					return opts
+				}(),
+			},
+		},
+	}
+
+	tests := []struct {
+		naming core.NamingStrategy
+		tables []string
+		book   string
+		column string
+		enum   string
+		ref    string
+		index  string
+	}{
+		{
+			naming: core.NamingPreserve,
+			tables: []string{"LibraryBranch", "Book", "Shelf"},
+			book:   "Book",
+			column: "branchId",
+			enum:   "BookKind",
+			ref:    "LibraryBranch",
+			index:  "Book_branchId_idx",
+		},
+		{
+			naming: core.NamingSnakeCase,
+			tables: []string{"Shelf", "library_branch", "book"},
+			book:   "book",
+			column: "branch_id",
+			enum:   "book_kind",
+			ref:    "library_branch",
+			index:  "book_branch_id_idx",
+		},
+		{
+			naming: core.NamingPlural,
+			tables: []string{"Shelf", "library_branches", "books"},
+			book:   "books",
+			column: "branch_id",
+			enum:   "book_kind",
+			ref:    "library_branches",
+			index:  "books_branch_id_idx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.naming), func(t *testing.T) {
+			t.Parallel()
+
+			sb := buildSchema(t, proto.CloneOf(file), template.Options{Naming: tt.naming})
+
+			tables := make([]string, 0, len(sb.Schema.Tables))
+			for _, table := range sb.Schema.Tables {
+				tables = append(tables, table.Name)
+			}
+
+			if !slices.Equal(tables, tt.tables) {
+				t.Fatalf("tables = %v, want %v", tables, tt.tables)
+			}
+
+			book := sb.Schema.TableByName(tt.book)
+
+			if book.ColumnByName(tt.column) == nil {
+				t.Errorf("column %s not built", tt.column)
+			}
+
+			if kind := book.ColumnByName("kind"); kind == nil || kind.Type != core.ColumnType(core.PostgreSQL{}.QuoteIdent(tt.enum)) {
+				t.Errorf("column kind = %+v, want type %s", kind, tt.enum)
+			}
+
+			if !slices.ContainsFunc(book.Constraints, func(c core.Constraint) bool {
+				return c.Type == core.ForeignKeyConstraint && c.References.Table == tt.ref
+			}) {
+				t.Errorf("foreign key to %s not built: %+v", tt.ref, book.Constraints)
+			}
+
+			if len(book.Indexes) != 1 || book.Indexes[0].Name != tt.index {
+				t.Errorf("indexes = %+v, want %s", book.Indexes, tt.index)
+			}
+		})
+	}
+}
+
+func TestSchemaOrder(t *testing.T) {
+	t.Parallel()
+
+	ref := func(name string, number int32, references string) *descriptorpb.FieldDescriptorProto {
+		return field(name, number, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+			&sqlcpb.FieldConstraints{References: references},
+		))
+	}
+	id := field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, sqlcOpts(
+		&sqlcpb.FieldConstraints{Primary: true},
+	))
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/zoo.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"sqlc/sqlc.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:  proto.String("zoo"),
+				Field: []*descriptorpb.FieldDescriptorProto{id, ref("keeper_id", 2, "keeper.id")},
+			},
+			{
+				Name: proto.String("keeper"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					id, ref("zoo_id", 2, "zoo.id"), ref("boss_id", 3, "keeper.id"),
+				},
+			},
+			{
+				Name:  proto.String("animal"),
+				Field: []*descriptorpb.FieldDescriptorProto{id, ref("zoo_id", 2, "zoo.id")},
+			},
+			{
+				Name:  proto.String("bird"),
+				Field: []*descriptorpb.FieldDescriptorProto{id},
+			},
+		},
+	}
+
+	sb := buildSchema(t, file, template.Options{})
+
+	tables := make([]string, 0, len(sb.Schema.Tables))
+	for _, table := range sb.Schema.Tables {
+		tables = append(tables, table.Name)
+	}
+
+	if want := []string{"keeper", "zoo", "animal", "bird"}; !slices.Equal(tables, want) {
+		t.Errorf("tables = %v, want %v", tables, want)
+	}
+
+	if len(sb.Schema.ForeignKeys) != 1 {
+		t.Fatalf("foreign keys = %+v, want one", sb.Schema.ForeignKeys)
+	}
+
+	fk := sb.Schema.ForeignKeys[0]
+	if fk.Table != "keeper" || fk.Constraint.Name != "keeper_zoo_id_fkey" ||
+		fk.Constraint.References.Table != "zoo" {
+		t.Errorf("foreign key = %+v, want keeper_zoo_id_fkey on keeper referencing zoo", fk)
+	}
+
+	if slices.ContainsFunc(sb.Schema.TableByName("keeper").Constraints, func(c core.Constraint) bool {
+		return c.References != nil && c.References.Table == "zoo"
+	}) {
+		t.Error("foreign key closing the cycle is still declared in keeper")
+	}
+}
+
+func TestGenerateQueries(t *testing.T) {
+	t.Parallel()
+
+	message := func(name string) *descriptorpb.DescriptorProto {
+		return &descriptorpb.DescriptorProto{
+			Name: proto.String(name),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_INT64, nil),
+			},
+		}
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("test/shop.proto"),
+		Package:     proto.String("test"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{message("Order"), message("Item")},
+	}
+
+	p := newPlugin(t, file)
+
+	sb := converter.NewSchemaBuilder(template.Options{})
+	if err := sb.Build(p); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"test/shop.proto"}; !slices.Equal(sb.Sources, want) {
+		t.Errorf("sources = %v, want %v", sb.Sources, want)
+	}
+
+	err := converter.GenerateQueries(
+		p,
+		sb.Schema,
+		sb.MessagesByFile,
+		sb.QueryNames,
+		sb.TablesByMessage,
+		sb.ChildrenByMessage,
+		template.New(),
+		template.Options{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := p.Response().GetFile()
+	if len(files) != 1 || files[0].GetName() != "example.com/test/shop.sql" {
+		t.Fatalf("generated files = %v, want example.com/test/shop.sql", files)
+	}
+
+	content := files[0].GetContent()
+	if want := "-- source:\n--   test/shop.proto\n"; strings.Count(content, want) != 1 {
+		t.Errorf("output does not contain %q once:\n%s", want, content)
+	}
+
+	order, item := strings.Index(content, "-- name: GetOrder"), strings.Index(content, "-- name: GetItem")
+	if order < 0 || item < order {
+		t.Errorf("output does not hold the order then item queries:\n%s", content)
+	}
+}
@@ -0,0 +1,171 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package converter
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/core"
+)
+
+// restrictSchema drops the defaults, indexes, and index and foreign key
+// options that the dialect does not support, warning about each of them, so
+// that the schema still loads without them. Keys on columns the dialect
+// cannot index are an error, as dropping them would lose integrity.
+func restrictSchema(schema *core.Schema, dialect core.Dialect) error {
+	for i := range schema.Tables {
+		table := &schema.Tables[i]
+
+		for j := range table.Columns {
+			restrictDefault(&table.Columns[j], table.Name, dialect)
+		}
+
+		table.Indexes = slices.DeleteFunc(table.Indexes, func(index core.Index) bool {
+			return !indexable(table, index.Columns, dialect)
+		})
+
+		for j := range table.Indexes {
+			restrictIndex(&table.Indexes[j], table.Name, dialect)
+		}
+
+		for j := range table.Constraints {
+			if err := checkKey(table, table.Constraints[j], dialect); err != nil {
+				return err
+			}
+
+			restrictConstraint(&table.Constraints[j], table.Name, dialect)
+		}
+	}
+
+	for i := range schema.ForeignKeys {
+		fk := &schema.ForeignKeys[i]
+
+		if table := schema.TableByName(fk.Table); table != nil {
+			if err := checkKey(table, fk.Constraint, dialect); err != nil {
+				return err
+			}
+		}
+
+		restrictConstraint(&fk.Constraint, fk.Table, dialect)
+	}
+
+	return nil
+}
+
+// restrictDefault adapts the default of a column to the dialect, dropping it
+// if the column type takes no such default.
+func restrictDefault(column *core.Column, table string, dialect core.Dialect) {
+	if column.DefaultValue == "" {
+		return
+	}
+
+	value, ok := dialect.DefaultValue(column.Type, column.DefaultValue)
+	if !ok {
+		slog.Warn(
+			"dropping default unsupported by the dialect",
+			slog.String("table", table),
+			slog.String("column", column.Name),
+			slog.String("default", column.DefaultValue),
+			slog.String("dialect", dialect.Name()),
+		)
+	}
+
+	column.DefaultValue = value
+}
+
+// indexable reports whether the dialect can index the given columns of a
+// table, warning about the ones it cannot.
+func indexable(table *core.Table, columns []string, dialect core.Dialect) bool {
+	for _, name := range columns {
+		column := table.ColumnByName(name)
+		if column == nil || dialect.Indexable(column.Type) {
+			continue
+		}
+
+		slog.Warn(
+			"dropping index on a column the dialect cannot index",
+			slog.String("table", table.Name),
+			slog.String("column", name),
+			slog.String("dialect", dialect.Name()),
+		)
+
+		return false
+	}
+
+	return true
+}
+
+// checkKey returns an error if a primary key, unique or foreign key
+// constraint covers a column that the dialect cannot index.
+func checkKey(table *core.Table, constraint core.Constraint, dialect core.Dialect) error {
+	if constraint.Type == core.CheckConstraint {
+		return nil
+	}
+
+	for _, name := range constraint.Columns {
+		if column := table.ColumnByName(name); column != nil && !dialect.Indexable(column.Type) {
+			return fmt.Errorf(
+				"%w: %s %s(%s) in %s, add a max_len rule to bound its length",
+				ErrUnindexableKey, constraint.Type, table.Name, name, dialect.Name(),
+			)
+		}
+	}
+
+	return nil
+}
+
+// restrictIndex drops the options of an index unsupported by the dialect.
+func restrictIndex(index *core.Index, table string, dialect core.Dialect) {
+	warn := func(option string) {
+		slog.Warn(
+			"dropping index option unsupported by the dialect",
+			slog.String("table", table),
+			slog.String("index", index.Name),
+			slog.String("option", option),
+			slog.String("dialect", dialect.Name()),
+		)
+	}
+
+	if index.Method != "" && !dialect.Supports(core.FeatureIndexMethods) {
+		warn("method")
+
+		index.Method = ""
+	}
+
+	if len(index.Include) > 0 && !dialect.Supports(core.FeatureIndexInclude) {
+		warn("include")
+
+		index.Include = nil
+	}
+
+	if index.Where != "" && !dialect.Supports(core.FeaturePartialIndexes) {
+		warn("where")
+
+		index.Where = ""
+	}
+}
+
+// restrictConstraint drops the options of a constraint unsupported by the
+// dialect.
+func restrictConstraint(constraint *core.Constraint, table string, dialect core.Dialect) {
+	if constraint.References == nil || !constraint.References.Deferrable ||
+		dialect.Supports(core.FeatureDeferrable) {
+		return
+	}
+
+	slog.Warn(
+		"dropping deferrable foreign key unsupported by the dialect",
+		slog.String("table", table),
+		slog.String("columns", strings.Join(constraint.Columns, ", ")),
+		slog.String("dialect", dialect.Name()),
+	)
+
+	references := *constraint.References
+	references.Deferrable = false
+	constraint.References = &references
+}
@@ -0,0 +1,294 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package converter
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/core"
+	sqlcpb "github.com/pablojimpas/protoc-gen-sqlc/internal/gen/sqlc"
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/sqlc/template"
+)
+
+// Name of the column of an enum lookup table holding the value name.
+const enumNameColumn = "name"
+
+// enumOptions returns the sqlc extension of an enum, or nil if the enum has
+// none.
+func enumOptions(enum *protogen.Enum) *sqlcpb.EnumOptions {
+	opts := enum.Desc.Options()
+	if !proto.HasExtension(opts, sqlcpb.E_Enum) {
+		return nil
+	}
+
+	ext, ok := proto.GetExtension(opts, sqlcpb.E_Enum).(*sqlcpb.EnumOptions)
+	if !ok {
+		slog.Warn(
+			"invalid extension type for enum",
+			slog.String("enum", string(enum.Desc.Name())),
+		)
+
+		return nil
+	}
+
+	return ext
+}
+
+// enumName returns the SQL name of an enum, qualified with the names of the
+// messages it is nested in so that Book.Format becomes Book_Format.
+func enumName(enum *protogen.Enum, opts template.Options) string {
+	name := strings.TrimPrefix(
+		string(enum.Desc.FullName()),
+		string(enum.Desc.ParentFile().Package())+".",
+	)
+
+	return qualifiedName(strings.Split(name, "."), opts)
+}
+
+// nestedEnums returns the enums declared in a message and, recursively, in
+// its nested messages.
+func nestedEnums(message *protogen.Message) []*protogen.Enum {
+	enums := slices.Clone(message.Enums)
+	for _, nested := range message.Messages {
+		enums = append(enums, nestedEnums(nested)...)
+	}
+
+	return enums
+}
+
+// enumStorage returns how an enum is stored, which the enum option selects
+// over the enum_storage plugin option.
+func enumStorage(enum *protogen.Enum, opts template.Options) core.EnumStorage {
+	switch enumOptions(enum).GetStorage() {
+	case sqlcpb.EnumStorage_ENUM_STORAGE_NATIVE:
+		return core.EnumStorageNative
+	case sqlcpb.EnumStorage_ENUM_STORAGE_TEXT:
+		return core.EnumStorageText
+	case sqlcpb.EnumStorage_ENUM_STORAGE_SMALLINT:
+		return core.EnumStorageSmallInt
+	case sqlcpb.EnumStorage_ENUM_STORAGE_TABLE:
+		return core.EnumStorageTable
+	default:
+		if opts.EnumStorage != "" {
+			return opts.EnumStorage
+		}
+
+		return core.EnumStorageNative
+	}
+}
+
+// sqlEnum converts a protobuf enum to a SQL enum holding the stored values.
+func sqlEnum(protoEnum *protogen.Enum, opts template.Options) (core.Enum, error) {
+	values := make([]core.EnumValue, 0, len(protoEnum.Values))
+	for _, v := range enumValues(protoEnum) {
+		name, _ := enumValueName(protoEnum, v)
+
+		if slices.ContainsFunc(values, func(e core.EnumValue) bool { return e.Name == name }) {
+			return core.Enum{}, fmt.Errorf("duplicate enum value name %s", name)
+		}
+
+		values = append(values, core.EnumValue{Name: name, Number: int32(v.Number())})
+	}
+
+	return core.Enum{
+		Name:    enumName(protoEnum, opts),
+		Values:  values,
+		Storage: enumStorage(protoEnum, opts),
+	}, nil
+}
+
+// enumColumnType returns the type of the columns holding an enum. Native
+// enums are rendered by the dialect, either as the name of their type or
+// inline with their values, and names as strings long enough for them.
+func enumColumnType(enum *protogen.Enum, opts template.Options) core.ColumnType {
+	// Duplicate values are reported when building the enum itself
+	values, _ := sqlEnum(enum, opts)
+
+	switch values.Storage {
+	case core.EnumStorageText, core.EnumStorageTable:
+		return opts.SQLDialect().StringType(uint64(values.MaxNameLength()))
+	case core.EnumStorageSmallInt:
+		return core.SmallIntType
+	default:
+		return core.ColumnType(opts.SQLDialect().EnumType(values))
+	}
+}
+
+// enumValueOptions returns the sqlc extension of an enum value, or nil if the
+// value has none.
+func enumValueOptions(value protoreflect.EnumValueDescriptor) *sqlcpb.EnumValueOptions {
+	opts := value.Options()
+	if !proto.HasExtension(opts, sqlcpb.E_EnumValue) {
+		return nil
+	}
+
+	ext, ok := proto.GetExtension(opts, sqlcpb.E_EnumValue).(*sqlcpb.EnumValueOptions)
+	if !ok {
+		slog.Warn(
+			"invalid extension type for enum value",
+			slog.String("value", string(value.Name())),
+		)
+
+		return nil
+	}
+
+	return ext
+}
+
+// enumValueName returns the stored name of an enum value, and false if the
+// value is omitted and stored as NULL.
+func enumValueName(enum *protogen.Enum, value protoreflect.EnumValueDescriptor) (string, bool) {
+	ext := enumOptions(enum)
+
+	if ext.GetOmitUnspecified() && value.Number() == 0 {
+		return "", false
+	}
+
+	if name := enumValueOptions(value).GetName(); name != "" {
+		return name, true
+	}
+
+	name := string(value.Name())
+
+	if ext.GetStripPrefix() {
+		prefix := strings.ToUpper(snakeCase(string(enum.Desc.Name()))) + "_"
+		if stripped := strings.TrimPrefix(name, prefix); stripped != "" {
+			name = stripped
+		}
+	}
+
+	if ext.GetLowercase() {
+		name = strings.ToLower(name)
+	}
+
+	return name, true
+}
+
+// enumValues returns the value descriptors of an enum, leaving out omitted
+// values.
+func enumValues(enum *protogen.Enum) []protoreflect.EnumValueDescriptor {
+	values := make([]protoreflect.EnumValueDescriptor, 0, len(enum.Values))
+	for _, v := range enum.Values {
+		if _, ok := enumValueName(enum, v.Desc); ok {
+			values = append(values, v.Desc)
+		}
+	}
+
+	return values
+}
+
+// enumLiteral renders an enum value as stored in its columns: its number for
+// SMALLINT storage and its quoted name otherwise. Omitted values render as an
+// empty string.
+func enumLiteral(
+	enum *protogen.Enum,
+	value protoreflect.EnumValueDescriptor,
+	storage core.EnumStorage,
+	dialect core.Dialect,
+) string {
+	name, ok := enumValueName(enum, value)
+	if !ok {
+		return ""
+	}
+
+	if storage == core.EnumStorageSmallInt {
+		return strconv.Itoa(int(value.Number()))
+	}
+
+	return dialect.QuoteLiteral(name)
+}
+
+// enumLiterals renders a comma-separated list of enum values, leaving out
+// omitted values.
+func enumLiterals(
+	enum *protogen.Enum,
+	values []protoreflect.EnumValueDescriptor,
+	storage core.EnumStorage,
+	dialect core.Dialect,
+) string {
+	literals := make([]string, 0, len(values))
+	for _, v := range values {
+		if literal := enumLiteral(enum, v, storage, dialect); literal != "" {
+			literals = append(literals, literal)
+		}
+	}
+
+	return strings.Join(literals, ", ")
+}
+
+// enumDefault renders a (sqlc.field).default naming an enum value as stored in
+// its columns.
+func enumDefault(enum *protogen.Enum, value string, opts template.Options) string {
+	storage := enumStorage(enum, opts)
+
+	if v := enum.Desc.Values().ByName(protoreflect.Name(value)); v != nil {
+		return enumLiteral(enum, v, storage, opts.SQLDialect())
+	}
+
+	if storage == core.EnumStorageSmallInt {
+		return value
+	}
+
+	return opts.SQLDialect().QuoteLiteral(value)
+}
+
+// enumTypeChecks returns the checks that keep an enum column within the
+// values of the enum. Text columns only ever hold value names, while the
+// smallint columns of open enums may carry unknown numbers and are only
+// checked for closed enums. Native types and lookup tables restrict the
+// values already.
+func enumTypeChecks(enum *protogen.Enum, column string, opts template.Options) []string {
+	if enum == nil {
+		return nil
+	}
+
+	storage := enumStorage(enum, opts)
+	if storage != core.EnumStorageText &&
+		(storage != core.EnumStorageSmallInt || !enum.Desc.IsClosed()) {
+		return nil
+	}
+
+	literals := enumLiterals(enum, enumValues(enum), storage, opts.SQLDialect())
+
+	return []string{fmt.Sprintf("%s IN (%s)", column, literals)}
+}
+
+// checkedEnumStorage reports whether the values of an enum storage are only
+// restricted by checks.
+func checkedEnumStorage(storage core.EnumStorage) bool {
+	return storage == core.EnumStorageText || storage == core.EnumStorageSmallInt
+}
+
+// enumReferences returns the foreign keys from enum columns to the lookup
+// tables of enums stored in tables.
+func enumReferences(fields []columnField, opts template.Options) []core.Constraint {
+	var constraints []core.Constraint
+
+	for _, field := range fields {
+		if field.Enum == nil || field.Desc.IsList() || field.Desc.IsMap() ||
+			enumStorage(field.Enum, opts) != core.EnumStorageTable {
+			continue
+		}
+
+		constraints = append(constraints, core.Constraint{
+			Type:    core.ForeignKeyConstraint,
+			Columns: []string{field.Column},
+			References: &core.Reference{
+				Table:   enumName(field.Enum, opts),
+				Columns: []string{enumNameColumn},
+			},
+		})
+	}
+
+	return constraints
+}
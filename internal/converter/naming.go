@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package converter
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/core"
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/sqlc/template"
+)
+
+// snakeCase converts a CamelCase protobuf name to snake_case, keeping
+// acronyms together: HTTPRequest becomes http_request.
+func snakeCase(name string) string {
+	runes := []rune(name)
+
+	var b strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+
+			if prev != '_' && (unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && nextLower)) {
+				b.WriteByte('_')
+			}
+		}
+
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+// columnName returns the SQL name of a column named after a protobuf field.
+func columnName(name string, opts template.Options) string {
+	if preserveNames(opts) {
+		return name
+	}
+
+	return snakeCase(name)
+}
+
+// qualifiedName joins the parts of a qualified protobuf name, such as the
+// names of a nested message and its parents, into a SQL identifier.
+func qualifiedName(parts []string, opts template.Options) string {
+	if preserveNames(opts) {
+		return strings.Join(parts, "_")
+	}
+
+	snake := make([]string, 0, len(parts))
+	for _, part := range parts {
+		snake = append(snake, snakeCase(part))
+	}
+
+	return strings.Join(snake, "_")
+}
+
+// referencedTable returns the SQL name of a table referenced by name in a
+// (sqlc.field).references option when no message of that name exists.
+func referencedTable(name string, opts template.Options) string {
+	if preserveNames(opts) {
+		return name
+	}
+
+	return pluralTable(snakeCase(name), opts)
+}
+
+// pluralTable pluralises a table name if the naming strategy asks for it.
+func pluralTable(name string, opts template.Options) string {
+	if opts.Naming != core.NamingPlural {
+		return name
+	}
+
+	return pluralize(name)
+}
+
+func preserveNames(opts template.Options) bool {
+	return opts.Naming == "" || opts.Naming == core.NamingPreserve
+}
+
+// pluralize returns the English plural of a snake_case name, changing only
+// its last word: library_shelf becomes library_shelves.
+func pluralize(name string) string {
+	switch {
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"),
+		strings.HasSuffix(name, "z"), strings.HasSuffix(name, "ch"),
+		strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !isVowel(name[len(name)-2]):
+		return name[:len(name)-1] + "ies"
+	case strings.HasSuffix(name, "lf"):
+		return name[:len(name)-1] + "ves"
+	case strings.HasSuffix(name, "fe"):
+		return name[:len(name)-2] + "ves"
+	default:
+		return name + "s"
+	}
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiou", c) >= 0
+}
+
+// columnNames returns the SQL names of columns listed by field name in an
+// option.
+func columnNames(names []string, opts template.Options) []string {
+	if preserveNames(opts) {
+		return names
+	}
+
+	columns := make([]string, 0, len(names))
+	for _, name := range names {
+		columns = append(columns, columnName(name, opts))
+	}
+
+	return columns
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package converter
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/core"
+)
+
+// State of a table while ordering tables by their foreign keys.
+type visitState int
+
+const (
+	unvisited visitState = iota
+	visiting
+	visited
+)
+
+// sortSchema orders the schema so that the output is stable and loads in a
+// single pass. Extensions and enums are sorted by name, and tables come after
+// the tables they reference, ties being broken by name. Foreign keys that
+// close a cycle are moved out of their table, to be added once every table
+// exists.
+func sortSchema(schema *core.Schema) {
+	slices.Sort(schema.Extensions)
+	slices.SortFunc(schema.Enums, func(a, b core.Enum) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	tables := slices.Clone(schema.Tables)
+	slices.SortFunc(tables, func(a, b core.Table) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	byName := make(map[string]*core.Table, len(tables))
+	for i := range tables {
+		byName[tables[i].Name] = &tables[i]
+	}
+
+	var (
+		sorted = make([]core.Table, 0, len(tables))
+		state  = make(map[string]visitState, len(tables))
+		visit  func(table *core.Table)
+	)
+
+	visit = func(table *core.Table) {
+		state[table.Name] = visiting
+
+		constraints := make([]core.Constraint, 0, len(table.Constraints))
+
+		for _, constraint := range table.Constraints {
+			referenced, ok := dependency(constraint, table, byName)
+			if ok {
+				switch state[referenced.Name] {
+				case visiting:
+					constraint.Name = cmp.Or(constraint.Name, identifierName(
+						table.Name, strings.Join(constraint.Columns, "_"), "fkey",
+					))
+					schema.ForeignKeys = append(schema.ForeignKeys, core.ForeignKey{
+						Table:      table.Name,
+						Constraint: constraint,
+					})
+
+					continue
+				case unvisited:
+					visit(referenced)
+				case visited:
+				}
+			}
+
+			constraints = append(constraints, constraint)
+		}
+
+		table.Constraints = constraints
+		state[table.Name] = visited
+		sorted = append(sorted, *table)
+	}
+
+	for i := range tables {
+		if state[tables[i].Name] == unvisited {
+			visit(&tables[i])
+		}
+	}
+
+	schema.Tables = sorted
+}
+
+// dependency returns the other table of the schema a foreign key points
+// to. References to the table itself or to enum lookup tables, which are
+// created before every table, impose no order.
+func dependency(
+	constraint core.Constraint,
+	table *core.Table,
+	byName map[string]*core.Table,
+) (*core.Table, bool) {
+	if constraint.Type != core.ForeignKeyConstraint || constraint.References == nil ||
+		constraint.References.Table == table.Name {
+		return nil, false
+	}
+
+	referenced, ok := byName[constraint.References.Table]
+
+	return referenced, ok
+}
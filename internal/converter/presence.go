@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package converter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/core"
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/sqlc/template"
+)
+
+// applyDescriptor applies the nullability and default value declared by the
+// field itself: proto2 required fields, or editions LEGACY_REQUIRED ones, are
+// NOT NULL and explicit defaults become column defaults.
+func applyDescriptor(field *protogen.Field, column *core.Column, opts template.Options) {
+	if field.Desc.Cardinality() == protoreflect.Required {
+		column.NotNull = true
+	}
+
+	// An explicit (sqlc.field).default wins over the protobuf one
+	if field.Desc.HasDefault() && column.DefaultValue == "" {
+		column.DefaultValue = defaultLiteral(field, opts)
+	}
+}
+
+// applyPresence makes a column NOT NULL when its field does not track
+// presence, defaulting to the zero value that stands for an unset field.
+func applyPresence(field *protogen.Field, column *core.Column, opts template.Options) {
+	if field.Desc.HasPresence() || field.Desc.IsList() || field.Desc.IsMap() {
+		return
+	}
+
+	// The zero value of the enum is stored as NULL
+	if field.Enum != nil && enumOptions(field.Enum).GetOmitUnspecified() {
+		return
+	}
+
+	column.NotNull = true
+
+	// Keys are always set explicitly, and the zero value of a string is not
+	// a valid UUID
+	if column.DefaultValue != "" || column.Type == core.UUIDType ||
+		fieldConstraints(field).GetPrimary() {
+		return
+	}
+
+	column.DefaultValue = defaultLiteral(field, opts)
+}
+
+// defaultLiteral renders the default value of a field, or its zero value if
+// it has none, as a SQL literal.
+func defaultLiteral(field *protogen.Field, opts template.Options) string {
+	value := field.Desc.Default()
+
+	switch field.Desc.Kind() {
+	case protoreflect.EnumKind:
+		enumValue := field.Desc.Enum().Values().ByNumber(value.Enum())
+		if enumValue == nil {
+			return ""
+		}
+
+		return enumLiteral(field.Enum, enumValue, enumStorage(field.Enum, opts), opts.SQLDialect())
+	case protoreflect.StringKind:
+		return opts.SQLDialect().QuoteLiteral(value.String())
+	case protoreflect.BytesKind:
+		return opts.SQLDialect().BytesLiteral(value.Bytes())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f := value.Float()
+
+		switch {
+		case math.IsInf(f, 1):
+			return "'Infinity'"
+		case math.IsInf(f, -1):
+			return "'-Infinity'"
+		case math.IsNaN(f):
+			return "'NaN'"
+		}
+
+		bitSize := 64
+		if field.Desc.Kind() == protoreflect.FloatKind {
+			bitSize = 32
+		}
+
+		return strconv.FormatFloat(f, 'g', -1, bitSize)
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
@@ -0,0 +1,407 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package converter
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/core"
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/sqlc/template"
+)
+
+// buildChecks translates the protovalidate rules of every field into named
+// CHECK constraints, one per column.
+func buildChecks(
+	fields []columnField,
+	table string,
+	opts template.Options,
+) ([]core.Constraint, error) {
+	var constraints []core.Constraint
+
+	for _, field := range fields {
+		column := field.Column
+		quoted := opts.SQLDialect().QuoteIdent(column)
+
+		expressions := append(
+			typeChecks(field.Field, quoted, opts),
+			checkExpressions(field.Field, quoted, opts)...,
+		)
+		if len(expressions) == 0 {
+			continue
+		}
+
+		constraints = append(constraints, core.Constraint{
+			Type:       core.CheckConstraint,
+			Name:       identifierName(table, column, "check"),
+			Columns:    []string{column},
+			Expression: strings.Join(expressions, " AND "),
+		})
+	}
+
+	return append(constraints, oneofChecks(fields, table, opts)...), nil
+}
+
+// isRealOneof reports whether a oneof was declared as such, rather than
+// synthesized for a proto3 optional field.
+func isRealOneof(oneof *protogen.Oneof) bool {
+	return oneof != nil && !oneof.Desc.IsSynthetic()
+}
+
+// oneofChecks returns a CHECK constraint per oneof allowing at most one of its
+// member columns to be set, or exactly one if the oneof is required.
+func oneofChecks(fields []columnField, table string, opts template.Options) []core.Constraint {
+	type oneofColumns struct {
+		oneof   *protogen.Oneof
+		name    string
+		columns []string
+	}
+
+	var oneofs []*oneofColumns
+
+	// The same oneof may be embedded several times, so it is told apart by
+	// its prefixed name
+	byName := make(map[string]*oneofColumns)
+
+	for _, field := range fields {
+		if !isRealOneof(field.Oneof) {
+			continue
+		}
+
+		name := field.Prefix + columnName(string(field.Oneof.Desc.Name()), opts)
+
+		o, ok := byName[name]
+		if !ok {
+			o = &oneofColumns{oneof: field.Oneof, name: name}
+			byName[name] = o
+			oneofs = append(oneofs, o)
+		}
+
+		o.columns = append(o.columns, field.Column)
+	}
+
+	dialect := opts.SQLDialect()
+	constraints := make([]core.Constraint, 0, len(oneofs))
+
+	for _, o := range oneofs {
+		// Members embedded as several columns cannot be counted as one
+		if len(o.columns) != len(o.oneof.Fields) {
+			continue
+		}
+
+		op := "<="
+		if oneofRules(o.oneof).GetRequired() {
+			op = "="
+		}
+
+		constraints = append(constraints, core.Constraint{
+			Type:    core.CheckConstraint,
+			Name:    identifierName(table, o.name, "check"),
+			Columns: o.columns,
+			Expression: fmt.Sprintf(
+				"%s %s 1", dialect.CountNonNulls(core.QuoteIdents(dialect, o.columns)), op,
+			),
+		})
+	}
+
+	return constraints
+}
+
+// oneofRules returns the protovalidate rules of a oneof, or nil if the oneof
+// has none.
+func oneofRules(oneof *protogen.Oneof) *validate.OneofRules {
+	opts := oneof.Desc.Options()
+	if !proto.HasExtension(opts, validate.E_Oneof) {
+		return nil
+	}
+
+	ext, ok := proto.GetExtension(opts, validate.E_Oneof).(*validate.OneofRules)
+	if !ok {
+		slog.Warn(
+			"failed to get validate oneof extension",
+			slog.String("oneof", string(oneof.Desc.Name())),
+		)
+
+		return nil
+	}
+
+	return ext
+}
+
+// typeChecks returns the checks that keep a column within the range of the
+// protobuf type it was mapped from.
+func typeChecks(field *protogen.Field, column string, opts template.Options) []string {
+	if field.Desc.IsList() || field.Desc.IsMap() {
+		return nil
+	}
+
+	if value := wrapperValue(field); value != nil {
+		field = value
+	}
+
+	switch field.Desc.Kind() {
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return []string{column + " >= 0"}
+	case protoreflect.EnumKind:
+		return enumTypeChecks(field.Enum, column, opts)
+	default:
+		return nil
+	}
+}
+
+// fieldRules returns the protovalidate rules of a field, or nil if the field
+// has none.
+func fieldRules(field *protogen.Field) *validate.FieldRules {
+	opts := field.Desc.Options()
+	if !proto.HasExtension(opts, validate.E_Field) {
+		return nil
+	}
+
+	ext, ok := proto.GetExtension(opts, validate.E_Field).(*validate.FieldRules)
+	if !ok {
+		slog.Warn(
+			"failed to get validate field extension",
+			slog.String("field", string(field.Desc.Name())),
+		)
+
+		return nil
+	}
+
+	return ext
+}
+
+// checkExpressions returns the SQL boolean expressions equivalent to the
+// protovalidate rules of a field.
+func checkExpressions(field *protogen.Field, column string, opts template.Options) []string {
+	rules := fieldRules(field)
+	if rules == nil {
+		return nil
+	}
+
+	switch r := rules.GetType().(type) {
+	case *validate.FieldRules_Int32, *validate.FieldRules_Int64,
+		*validate.FieldRules_Uint32, *validate.FieldRules_Uint64,
+		*validate.FieldRules_Sint32, *validate.FieldRules_Sint64,
+		*validate.FieldRules_Fixed32, *validate.FieldRules_Fixed64,
+		*validate.FieldRules_Sfixed32, *validate.FieldRules_Sfixed64,
+		*validate.FieldRules_Float, *validate.FieldRules_Double:
+		m := rules.ProtoReflect()
+		typeRules := m.Get(m.WhichOneof(m.Descriptor().Oneofs().ByName("type"))).Message()
+
+		return numericChecks(column, typeRules)
+	case *validate.FieldRules_String_:
+		return stringChecks(column, r.String_, opts.SQLDialect())
+	case *validate.FieldRules_Repeated:
+		return repeatedChecks(column, r.Repeated, opts.SQLDialect())
+	case *validate.FieldRules_Enum:
+		return enumChecks(column, field.Enum, r.Enum, opts)
+	default:
+		return nil
+	}
+}
+
+// numericChecks handles the rules shared by every numeric rule message, which
+// differ only in the type of their values.
+func numericChecks(column string, rules protoreflect.Message) []string {
+	var checks []string
+
+	get := func(name protoreflect.Name) (protoreflect.Value, bool) {
+		fd := rules.Descriptor().Fields().ByName(name)
+		if fd == nil || !rules.Has(fd) {
+			return protoreflect.Value{}, false
+		}
+
+		return rules.Get(fd), true
+	}
+
+	if v, ok := get("const"); ok {
+		checks = append(checks, fmt.Sprintf("%s = %v", column, v.Interface()))
+	}
+
+	var (
+		lower, upper       string
+		lowerVal, upperVal float64
+	)
+
+	if v, ok := get("gt"); ok {
+		lower, lowerVal = fmt.Sprintf("%s > %v", column, v.Interface()), toFloat(v)
+	} else if v, ok := get("gte"); ok {
+		lower, lowerVal = fmt.Sprintf("%s >= %v", column, v.Interface()), toFloat(v)
+	}
+
+	if v, ok := get("lt"); ok {
+		upper, upperVal = fmt.Sprintf("%s < %v", column, v.Interface()), toFloat(v)
+	} else if v, ok := get("lte"); ok {
+		upper, upperVal = fmt.Sprintf("%s <= %v", column, v.Interface()), toFloat(v)
+	}
+
+	switch {
+	case lower != "" && upper != "" && lowerVal > upperVal:
+		// protovalidate treats an inverted range as an exclusion
+		checks = append(checks, fmt.Sprintf("(%s OR %s)", lower, upper))
+	default:
+		for _, check := range []string{lower, upper} {
+			if check != "" {
+				checks = append(checks, check)
+			}
+		}
+	}
+
+	for _, rule := range []struct {
+		name protoreflect.Name
+		op   string
+	}{{"in", "IN"}, {"not_in", "NOT IN"}} {
+		v, ok := get(rule.name)
+		if !ok {
+			continue
+		}
+
+		values := make([]string, 0, v.List().Len())
+		for i := range v.List().Len() {
+			values = append(values, fmt.Sprint(v.List().Get(i).Interface()))
+		}
+
+		checks = append(
+			checks,
+			fmt.Sprintf("%s %s (%s)", column, rule.op, strings.Join(values, ", ")),
+		)
+	}
+
+	return checks
+}
+
+// toFloat converts a numeric protoreflect value to a float64 for comparison.
+func toFloat(v protoreflect.Value) float64 {
+	switch n := v.Interface().(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint32:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		return 0
+	}
+}
+
+func stringChecks(column string, rules *validate.StringRules, dialect core.Dialect) []string {
+	var checks []string
+
+	if rules.HasConst() {
+		checks = append(checks, fmt.Sprintf("%s = %s", column, dialect.QuoteLiteral(rules.GetConst())))
+	}
+
+	if rules.HasLen() {
+		checks = append(checks, fmt.Sprintf("char_length(%s) = %d", column, rules.GetLen()))
+	}
+
+	if rules.HasMinLen() {
+		checks = append(checks, fmt.Sprintf("char_length(%s) >= %d", column, rules.GetMinLen()))
+	}
+
+	if rules.HasMaxLen() {
+		checks = append(checks, fmt.Sprintf("char_length(%s) <= %d", column, rules.GetMaxLen()))
+	}
+
+	if rules.HasPattern() {
+		checks = append(checks, dialect.Match(column, dialect.QuoteLiteral(rules.GetPattern())))
+	}
+
+	if in := rules.GetIn(); len(in) > 0 {
+		checks = append(checks, fmt.Sprintf("%s IN (%s)", column, quoteLiterals(in, dialect)))
+	}
+
+	if notIn := rules.GetNotIn(); len(notIn) > 0 {
+		checks = append(checks, fmt.Sprintf("%s NOT IN (%s)", column, quoteLiterals(notIn, dialect)))
+	}
+
+	return checks
+}
+
+func repeatedChecks(column string, rules *validate.RepeatedRules, dialect core.Dialect) []string {
+	var checks []string
+
+	if rules.HasMinItems() {
+		checks = append(checks, fmt.Sprintf("%s >= %d", dialect.Cardinality(column), rules.GetMinItems()))
+	}
+
+	if rules.HasMaxItems() {
+		checks = append(checks, fmt.Sprintf("%s <= %d", dialect.Cardinality(column), rules.GetMaxItems()))
+	}
+
+	return checks
+}
+
+// enumChecks maps enum rules, which refer to values by number, to the values
+// stored in the column.
+func enumChecks(
+	column string,
+	enum *protogen.Enum,
+	rules *validate.EnumRules,
+	opts template.Options,
+) []string {
+	if enum == nil {
+		return nil
+	}
+
+	storage := enumStorage(enum, opts)
+
+	literals := func(numbers []int32) string {
+		values := make([]protoreflect.EnumValueDescriptor, 0, len(numbers))
+		for _, n := range numbers {
+			if v := enum.Desc.Values().ByNumber(protoreflect.EnumNumber(n)); v != nil {
+				values = append(values, v)
+			}
+		}
+
+		return enumLiterals(enum, values, storage, opts.SQLDialect())
+	}
+
+	var checks []string
+
+	if value := literals([]int32{rules.GetConst()}); rules.HasConst() && value != "" {
+		checks = append(checks, fmt.Sprintf("%s = %s", column, value))
+	}
+
+	// Defined values may already be enforced by the column type or a check
+	if rules.GetDefinedOnly() && len(enumTypeChecks(enum, column, opts)) == 0 &&
+		checkedEnumStorage(storage) {
+		defined := enumLiterals(enum, enumValues(enum), storage, opts.SQLDialect())
+		checks = append(checks, fmt.Sprintf("%s IN (%s)", column, defined))
+	}
+
+	if in := literals(rules.GetIn()); in != "" {
+		checks = append(checks, fmt.Sprintf("%s IN (%s)", column, in))
+	}
+
+	if notIn := literals(rules.GetNotIn()); notIn != "" {
+		checks = append(checks, fmt.Sprintf("%s NOT IN (%s)", column, notIn))
+	}
+
+	return checks
+}
+
+// quoteLiterals renders a comma-separated list of SQL string literals.
+func quoteLiterals(values []string, dialect core.Dialect) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, dialect.QuoteLiteral(v))
+	}
+
+	return strings.Join(quoted, ", ")
+}
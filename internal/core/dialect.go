@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package core
+
+import "strings"
+
+type Dialect interface {
+	Renderer
+	QuerySyntax
+	Name() string
+	Supports(feature Feature) bool
+}
+
+type Renderer interface {
+	IsReserved(word string) bool
+	QuoteIdent(name string) string
+	QuoteLiteral(s string) string
+	BytesLiteral(b []byte) string
+	ColumnType(t ColumnType) string
+	StringType(maxLen uint64) ColumnType
+	EnumType(enum Enum) string
+	DefaultValue(t ColumnType, value string) (string, bool)
+	Indexable(t ColumnType) bool
+}
+
+type QuerySyntax interface {
+	Placeholder(n int) string
+	Upsert(conflict, update []string) string
+	Match(expr, pattern string) string
+	CountNonNulls(exprs []string) string
+	Cardinality(expr string) string
+}
+
+type Feature string
+
+const (
+	FeatureEnumTypes      Feature = "enum_types"
+	FeatureExtensions     Feature = "extensions"
+	FeatureReturning      Feature = "returning"
+	FeatureIndexMethods   Feature = "index_methods"
+	FeatureIndexInclude   Feature = "index_include"
+	FeaturePartialIndexes Feature = "partial_indexes"
+	FeatureDeferrable     Feature = "deferrable"
+)
+
+func Dialects() []Dialect {
+	return []Dialect{PostgreSQL{}, MySQL{}}
+}
+
+func needsQuoting(d Dialect, name string, foldsCase bool) bool {
+	if name == "" || d.IsReserved(name) {
+		return true
+	}
+
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r == '_', !foldsCase && r >= 'A' && r <= 'Z':
+		case (r >= '0' && r <= '9') || r == '$':
+			if i == 0 {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+
+	return false
+}
+
+func quote(name string, mark rune) string {
+	q := string(mark)
+
+	return q + strings.ReplaceAll(name, q, q+q) + q
+}
+
+func QuoteIdents(d Dialect, names []string) []string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, d.QuoteIdent(name))
+	}
+
+	return quoted
+}
@@ -0,0 +1,217 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package core
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Longest VARCHAR that fits in an index key of 3072 bytes with utf8mb4.
+const mysqlMaxKeyLength = 768
+
+type MySQL struct{}
+
+func (MySQL) Name() string {
+	return "mysql"
+}
+
+func (MySQL) IsReserved(word string) bool {
+	return isMySQLReserved(strings.ToLower(word))
+}
+
+func (d MySQL) QuoteIdent(name string) string {
+	if !needsQuoting(d, name, false) {
+		return name
+	}
+
+	return quote(name, '`')
+}
+
+func (MySQL) QuoteLiteral(s string) string {
+	// Backslashes start escape sequences unless NO_BACKSLASH_ESCAPES is set
+	return quote(strings.ReplaceAll(s, `\`, `\\`), '\'')
+}
+
+func (MySQL) BytesLiteral(b []byte) string {
+	return "X'" + hex.EncodeToString(b) + "'"
+}
+
+func (MySQL) Supports(Feature) bool {
+	return false
+}
+
+func (MySQL) ColumnType(t ColumnType) string {
+	if strings.HasSuffix(string(t), "[]") {
+		return "JSON"
+	}
+
+	switch t {
+	case TextType, VarcharType:
+		return "LONGTEXT"
+	case SerialType:
+		return "INT AUTO_INCREMENT"
+	case BigSerialType:
+		return "BIGINT AUTO_INCREMENT"
+	case NumericUint64Type:
+		return "DECIMAL(20)"
+	case NumericType:
+		return "DECIMAL(38, 9)"
+	case TimestampType:
+		return "DATETIME(6)"
+	case IntervalType:
+		return "BIGINT"
+	case JSONBType, HStoreType:
+		return "JSON"
+	case UUIDType:
+		return "CHAR(36)"
+	case ByteaType:
+		return "LONGBLOB"
+	case RealType:
+		return "FLOAT"
+	case DoublePrecisionType:
+		return "DOUBLE"
+	default:
+		return string(t)
+	}
+}
+
+func (MySQL) StringType(maxLen uint64) ColumnType {
+	if maxLen == 0 || maxLen > mysqlMaxKeyLength {
+		return TextType
+	}
+
+	return ColumnType(fmt.Sprintf("VARCHAR(%d)", maxLen))
+}
+
+func (d MySQL) EnumType(enum Enum) string {
+	values := make([]string, 0, len(enum.Values))
+	for _, value := range enum.Values {
+		values = append(values, d.QuoteLiteral(value.Name))
+	}
+
+	return "ENUM(" + strings.Join(values, ", ") + ")"
+}
+
+func (d MySQL) DefaultValue(t ColumnType, value string) (string, bool) {
+	switch d.ColumnType(t) {
+	case "JSON":
+		// Array and map literals have no JSON equivalent
+		return "", false
+	case "LONGTEXT", "LONGBLOB":
+		// Text and blob columns only take expressions as defaults
+		return "(" + value + ")", true
+	case "DATETIME(6)":
+		if strings.EqualFold(value, "'now()'") || strings.EqualFold(value, "'now'") {
+			return "CURRENT_TIMESTAMP(6)", true
+		}
+	case "FLOAT", "DOUBLE":
+		// Infinities and NaN, the only quoted floats, cannot be stored
+		if strings.HasPrefix(value, "'") {
+			return "", false
+		}
+	}
+
+	return value, true
+}
+
+func (d MySQL) Indexable(t ColumnType) bool {
+	switch d.ColumnType(t) {
+	case "JSON", "LONGTEXT", "LONGBLOB":
+		return false
+	default:
+		return true
+	}
+}
+
+func (MySQL) Placeholder(int) string {
+	return "?"
+}
+
+func (d MySQL) Upsert(conflict, update []string) string {
+	if len(update) == 0 && len(conflict) > 0 {
+		// A no-op assignment, as MySQL has no DO NOTHING
+		column := d.QuoteIdent(conflict[len(conflict)-1])
+
+		return "ON DUPLICATE KEY UPDATE " + column + " = " + column
+	}
+
+	assignments := make([]string, 0, len(update))
+	for _, column := range update {
+		column = d.QuoteIdent(column)
+		assignments = append(assignments, column+" = VALUES("+column+")")
+	}
+
+	return "ON DUPLICATE KEY UPDATE\n  " + strings.Join(assignments, ",\n  ")
+}
+
+func (MySQL) Match(expr, pattern string) string {
+	return expr + " REGEXP " + pattern
+}
+
+func (MySQL) CountNonNulls(exprs []string) string {
+	counts := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		counts = append(counts, "("+expr+" IS NOT NULL)")
+	}
+
+	return "(" + strings.Join(counts, " + ") + ")"
+}
+
+func (MySQL) Cardinality(expr string) string {
+	return "JSON_LENGTH(" + expr + ")"
+}
+
+func isMySQLReserved(word string) bool {
+	switch word {
+	case "accessible", "add", "all", "alter", "analyze", "and", "as", "asc",
+		"asensitive", "before", "between", "bigint", "binary", "blob", "both",
+		"by", "call", "cascade", "case", "change", "char", "character", "check",
+		"collate", "column", "condition", "constraint", "continue", "convert",
+		"create", "cross", "cube", "cume_dist", "current_date", "current_time",
+		"current_timestamp", "current_user", "cursor", "database", "databases",
+		"day_hour", "day_microsecond", "day_minute", "day_second", "dec",
+		"decimal", "declare", "default", "delayed", "delete", "dense_rank",
+		"desc", "describe", "deterministic", "distinct", "distinctrow", "div",
+		"double", "drop", "dual", "each", "else", "elseif", "empty", "enclosed",
+		"escaped", "except", "exists", "exit", "explain", "false", "fetch",
+		"first_value", "float", "float4", "float8", "for", "force", "foreign",
+		"from", "fulltext", "function", "generated", "get", "grant", "group",
+		"grouping", "groups", "having", "high_priority", "hour_microsecond",
+		"hour_minute", "hour_second", "if", "ignore", "in", "index", "infile",
+		"inner", "inout", "insensitive", "insert", "int", "int1", "int2", "int3",
+		"int4", "int8", "integer", "intersect", "interval", "into",
+		"io_after_gtids", "io_before_gtids", "is", "iterate", "join",
+		"json_table", "key", "keys", "kill", "lag", "last_value", "lateral",
+		"lead", "leading", "leave", "left", "like", "limit", "linear", "lines",
+		"load", "localtime", "localtimestamp", "lock", "long", "longblob",
+		"longtext", "loop", "low_priority", "master_bind",
+		"master_ssl_verify_server_cert", "match", "maxvalue", "mediumblob",
+		"mediumint", "mediumtext", "middleint", "minute_microsecond",
+		"minute_second", "mod", "modifies", "natural", "not",
+		"no_write_to_binlog", "nth_value", "ntile", "null", "numeric", "of",
+		"on", "optimize", "optimizer_costs", "option", "optionally", "or",
+		"order", "out", "outer", "outfile", "over", "partition", "percent_rank",
+		"precision", "primary", "procedure", "purge", "range", "rank", "read",
+		"reads", "read_write", "real", "recursive", "references", "regexp",
+		"release", "rename", "repeat", "replace", "require", "resignal",
+		"restrict", "return", "revoke", "right", "rlike", "row", "row_number",
+		"rows", "schema", "schemas", "second_microsecond", "select",
+		"sensitive", "separator", "set", "show", "signal", "smallint", "spatial",
+		"specific", "sql", "sqlexception", "sqlstate", "sqlwarning",
+		"sql_big_result", "sql_calc_found_rows", "sql_small_result", "ssl",
+		"starting", "stored", "straight_join", "system", "table", "terminated",
+		"then", "tinyblob", "tinyint", "tinytext", "to", "trailing", "trigger",
+		"true", "undo", "union", "unique", "unlock", "unsigned", "update",
+		"usage", "use", "using", "utc_date", "utc_time", "utc_timestamp",
+		"values", "varbinary", "varchar", "varcharacter", "varying", "virtual",
+		"when", "where", "while", "window", "with", "write", "xor", "year_month",
+		"zerofill":
+		return true
+	default:
+		return false
+	}
+}
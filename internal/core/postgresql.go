@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package core
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+type PostgreSQL struct{}
+
+func (PostgreSQL) Name() string {
+	return "postgresql"
+}
+
+func (PostgreSQL) IsReserved(word string) bool {
+	return isPostgreSQLReserved(strings.ToLower(word))
+}
+
+func (d PostgreSQL) QuoteIdent(name string) string {
+	if !needsQuoting(d, name, true) {
+		return name
+	}
+
+	return quote(name, '"')
+}
+
+func (PostgreSQL) QuoteLiteral(s string) string {
+	return quote(s, '\'')
+}
+
+func (PostgreSQL) BytesLiteral(b []byte) string {
+	return `'\x` + hex.EncodeToString(b) + "'"
+}
+
+func (PostgreSQL) Supports(Feature) bool {
+	return true
+}
+
+func (PostgreSQL) ColumnType(t ColumnType) string {
+	return string(t)
+}
+
+func (PostgreSQL) StringType(uint64) ColumnType {
+	// Lengths are checked by constraints, TEXT performing as well as VARCHAR
+	return TextType
+}
+
+func (d PostgreSQL) EnumType(enum Enum) string {
+	return d.QuoteIdent(enum.Name)
+}
+
+func (PostgreSQL) DefaultValue(_ ColumnType, value string) (string, bool) {
+	return value, true
+}
+
+func (PostgreSQL) Indexable(ColumnType) bool {
+	return true
+}
+
+func (PostgreSQL) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (d PostgreSQL) Upsert(conflict, update []string) string {
+	clause := fmt.Sprintf("ON CONFLICT (%s)", strings.Join(QuoteIdents(d, conflict), ", "))
+	if len(update) == 0 {
+		return clause + " DO NOTHING"
+	}
+
+	assignments := make([]string, 0, len(update))
+	for _, column := range update {
+		column = d.QuoteIdent(column)
+		assignments = append(assignments, column+" = EXCLUDED."+column)
+	}
+
+	return clause + " DO UPDATE SET\n  " + strings.Join(assignments, ",\n  ")
+}
+
+func (PostgreSQL) Match(expr, pattern string) string {
+	return expr + " ~ " + pattern
+}
+
+func (PostgreSQL) CountNonNulls(exprs []string) string {
+	return "num_nonnulls(" + strings.Join(exprs, ", ") + ")"
+}
+
+func (PostgreSQL) Cardinality(expr string) string {
+	return "cardinality(" + expr + ")"
+}
+
+func isPostgreSQLReserved(word string) bool {
+	switch word {
+	case "all", "analyse", "analyze", "and", "any", "array", "as", "asc",
+		"asymmetric", "authorization", "binary", "both", "case", "cast",
+		"check", "collate", "collation", "column", "concurrently",
+		"constraint", "create", "cross", "current_catalog", "current_date",
+		"current_role", "current_schema", "current_time", "current_timestamp",
+		"current_user", "default", "deferrable", "desc", "distinct", "do",
+		"else", "end", "except", "false", "fetch", "for", "foreign", "freeze",
+		"from", "full", "grant", "group", "having", "ilike", "in", "initially",
+		"inner", "intersect", "into", "is", "isnull", "join", "lateral",
+		"leading", "left", "like", "limit", "localtime", "localtimestamp",
+		"natural", "not", "notnull", "null", "offset", "on", "only", "or",
+		"order", "outer", "overlaps", "placing", "primary", "references",
+		"returning", "right", "select", "session_user", "similar", "some",
+		"symmetric", "system_user", "table", "tablesample", "then", "to",
+		"trailing", "true", "union", "unique", "user", "using", "variadic",
+		"verbose", "when", "where", "window", "with":
+		return true
+	default:
+		return false
+	}
+}
@@ -4,10 +4,14 @@
 
 package core
 
+import "unicode/utf8"
+
 type Schema struct {
-	Tables    []Table
-	Enums     []Enum
-	Sequences []Sequence
+	Extensions  []string
+	Tables      []Table
+	Enums       []Enum
+	Sequences   []Sequence
+	ForeignKeys []ForeignKey
 }
 
 func (s *Schema) TableByName(name string) *Table {
@@ -29,10 +33,42 @@ type Sequence struct {
 }
 
 type Enum struct {
+	Name    string
+	Values  []EnumValue
+	Storage EnumStorage
+}
+
+func (e Enum) MaxNameLength() int {
+	length := 0
+	for _, value := range e.Values {
+		length = max(length, utf8.RuneCountInString(value.Name))
+	}
+
+	return length
+}
+
+type EnumValue struct {
 	Name   string
-	Values []string
+	Number int32
 }
 
+type NamingStrategy string
+
+const (
+	NamingPreserve  NamingStrategy = "preserve"
+	NamingSnakeCase NamingStrategy = "snake_case"
+	NamingPlural    NamingStrategy = "plural"
+)
+
+type EnumStorage string
+
+const (
+	EnumStorageNative   EnumStorage = "native"
+	EnumStorageText     EnumStorage = "text"
+	EnumStorageSmallInt EnumStorage = "smallint"
+	EnumStorageTable    EnumStorage = "table"
+)
+
 type Table struct {
 	Name        string
 	Columns     []Column
@@ -40,21 +76,46 @@ type Table struct {
 	Indexes     []Index
 }
 
-func (s *Table) PrimaryKey() string {
+func (s *Table) PrimaryKey() []string {
 	for _, c := range s.Constraints {
 		if c.Type == PrimaryKeyConstraint {
-			return c.Columns[0]
+			return c.Columns
 		}
 	}
 
-	return "id"
+	return []string{"id"}
+}
+
+func (s *Table) ColumnByName(name string) *Column {
+	for i, c := range s.Columns {
+		if c.Name == name {
+			return &s.Columns[i]
+		}
+	}
+
+	return nil
 }
 
 type Index struct {
-	Name    string
-	Columns []string
+	Name       string
+	Columns    []string
+	Expression string
+	Unique     bool
+	Method     IndexMethod
+	Where      string
+	Include    []string
 }
 
+type IndexMethod string
+
+const (
+	IndexMethodBTree IndexMethod = "btree"
+	IndexMethodGIN   IndexMethod = "gin"
+	IndexMethodGiST  IndexMethod = "gist"
+	IndexMethodHash  IndexMethod = "hash"
+	IndexMethodBRIN  IndexMethod = "brin"
+)
+
 type Column struct {
 	Name         string
 	Type         ColumnType
@@ -64,26 +125,42 @@ type Column struct {
 
 type ColumnType string
 
+func (t ColumnType) Array() ColumnType {
+	return t + "[]"
+}
+
 const (
-	IntegerType      ColumnType = "INTEGER"
-	TextType         ColumnType = "TEXT"
-	SerialType       ColumnType = "SERIAL"
-	DateType         ColumnType = "DATE"
-	TimestampType    ColumnType = "TIMESTAMPTZ"
-	VarcharType      ColumnType = "VARCHAR"
-	VarcharArrayType ColumnType = "VARCHAR[]"
-	TextArrayType    ColumnType = "TEXT[]"
-	JSONBType        ColumnType = "JSONB"
-	UUIDType         ColumnType = "UUID"
-	BytesType        ColumnType = "BYTES"
-	FloatType        ColumnType = "FLOAT"
-	BooleanType      ColumnType = "BOOLEAN"
+	SmallIntType        ColumnType = "SMALLINT"
+	IntegerType         ColumnType = "INTEGER"
+	BigIntType          ColumnType = "BIGINT"
+	NumericUint64Type   ColumnType = "NUMERIC(20)"
+	NumericType         ColumnType = "NUMERIC"
+	TextType            ColumnType = "TEXT"
+	SerialType          ColumnType = "SERIAL"
+	BigSerialType       ColumnType = "BIGSERIAL"
+	DateType            ColumnType = "DATE"
+	TimeType            ColumnType = "TIME"
+	IntervalType        ColumnType = "INTERVAL"
+	TimestampType       ColumnType = "TIMESTAMPTZ"
+	VarcharType         ColumnType = "VARCHAR"
+	VarcharArrayType    ColumnType = "VARCHAR[]"
+	TextArrayType       ColumnType = "TEXT[]"
+	JSONBType           ColumnType = "JSONB"
+	HStoreType          ColumnType = "HSTORE"
+	UUIDType            ColumnType = "UUID"
+	ByteaType           ColumnType = "BYTEA"
+	RealType            ColumnType = "REAL"
+	DoublePrecisionType ColumnType = "DOUBLE PRECISION"
+	BooleanType         ColumnType = "BOOLEAN"
+	PointType           ColumnType = "POINT"
 )
 
 type Constraint struct {
+	Name       string
 	Type       ConstraintType
 	Columns    []string
 	References *Reference
+	Expression string
 }
 
 type ConstraintType string
@@ -92,13 +169,20 @@ const (
 	PrimaryKeyConstraint ConstraintType = "PRIMARY KEY"
 	ForeignKeyConstraint ConstraintType = "FOREIGN KEY"
 	UniqueConstraint     ConstraintType = "UNIQUE"
+	CheckConstraint      ConstraintType = "CHECK"
 )
 
+type ForeignKey struct {
+	Table      string
+	Constraint Constraint
+}
+
 type Reference struct {
-	Table    string
-	Columns  []string
-	OnDelete ForeignKeyAction
-	OnUpdate ForeignKeyAction
+	Table      string
+	Columns    []string
+	OnDelete   ForeignKeyAction
+	OnUpdate   ForeignKeyAction
+	Deferrable bool
 }
 
 type ForeignKeyAction string
@@ -26,12 +26,268 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type MapStorage int32
+
+const (
+	MapStorage_MAP_STORAGE_UNSPECIFIED MapStorage = 0
+	// A JSONB column holding the map as an object.
+	MapStorage_MAP_STORAGE_JSONB MapStorage = 1
+	// An HSTORE column. Only valid for map<string, string>.
+	MapStorage_MAP_STORAGE_HSTORE MapStorage = 2
+	// A child table with one row per entry, keyed by the parent primary key and
+	// the map key.
+	MapStorage_MAP_STORAGE_TABLE MapStorage = 3
+)
+
+// Enum value maps for MapStorage.
+var (
+	MapStorage_name = map[int32]string{
+		0: "MAP_STORAGE_UNSPECIFIED",
+		1: "MAP_STORAGE_JSONB",
+		2: "MAP_STORAGE_HSTORE",
+		3: "MAP_STORAGE_TABLE",
+	}
+	MapStorage_value = map[string]int32{
+		"MAP_STORAGE_UNSPECIFIED": 0,
+		"MAP_STORAGE_JSONB":       1,
+		"MAP_STORAGE_HSTORE":      2,
+		"MAP_STORAGE_TABLE":       3,
+	}
+)
+
+func (x MapStorage) Enum() *MapStorage {
+	p := new(MapStorage)
+	*p = x
+	return p
+}
+
+func (x MapStorage) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MapStorage) Descriptor() protoreflect.EnumDescriptor {
+	return file_sqlc_sqlc_proto_enumTypes[0].Descriptor()
+}
+
+func (MapStorage) Type() protoreflect.EnumType {
+	return &file_sqlc_sqlc_proto_enumTypes[0]
+}
+
+func (x MapStorage) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MapStorage.Descriptor instead.
+func (MapStorage) EnumDescriptor() ([]byte, []int) {
+	return file_sqlc_sqlc_proto_rawDescGZIP(), []int{0}
+}
+
+type ForeignKeyAction int32
+
+const (
+	ForeignKeyAction_FOREIGN_KEY_ACTION_UNSPECIFIED ForeignKeyAction = 0
+	ForeignKeyAction_FOREIGN_KEY_ACTION_NO_ACTION   ForeignKeyAction = 1
+	ForeignKeyAction_FOREIGN_KEY_ACTION_RESTRICT    ForeignKeyAction = 2
+	ForeignKeyAction_FOREIGN_KEY_ACTION_CASCADE     ForeignKeyAction = 3
+	ForeignKeyAction_FOREIGN_KEY_ACTION_SET_NULL    ForeignKeyAction = 4
+	ForeignKeyAction_FOREIGN_KEY_ACTION_SET_DEFAULT ForeignKeyAction = 5
+)
+
+// Enum value maps for ForeignKeyAction.
+var (
+	ForeignKeyAction_name = map[int32]string{
+		0: "FOREIGN_KEY_ACTION_UNSPECIFIED",
+		1: "FOREIGN_KEY_ACTION_NO_ACTION",
+		2: "FOREIGN_KEY_ACTION_RESTRICT",
+		3: "FOREIGN_KEY_ACTION_CASCADE",
+		4: "FOREIGN_KEY_ACTION_SET_NULL",
+		5: "FOREIGN_KEY_ACTION_SET_DEFAULT",
+	}
+	ForeignKeyAction_value = map[string]int32{
+		"FOREIGN_KEY_ACTION_UNSPECIFIED": 0,
+		"FOREIGN_KEY_ACTION_NO_ACTION":   1,
+		"FOREIGN_KEY_ACTION_RESTRICT":    2,
+		"FOREIGN_KEY_ACTION_CASCADE":     3,
+		"FOREIGN_KEY_ACTION_SET_NULL":    4,
+		"FOREIGN_KEY_ACTION_SET_DEFAULT": 5,
+	}
+)
+
+func (x ForeignKeyAction) Enum() *ForeignKeyAction {
+	p := new(ForeignKeyAction)
+	*p = x
+	return p
+}
+
+func (x ForeignKeyAction) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ForeignKeyAction) Descriptor() protoreflect.EnumDescriptor {
+	return file_sqlc_sqlc_proto_enumTypes[1].Descriptor()
+}
+
+func (ForeignKeyAction) Type() protoreflect.EnumType {
+	return &file_sqlc_sqlc_proto_enumTypes[1]
+}
+
+func (x ForeignKeyAction) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ForeignKeyAction.Descriptor instead.
+func (ForeignKeyAction) EnumDescriptor() ([]byte, []int) {
+	return file_sqlc_sqlc_proto_rawDescGZIP(), []int{1}
+}
+
+type IndexMethod int32
+
+const (
+	IndexMethod_INDEX_METHOD_UNSPECIFIED IndexMethod = 0
+	IndexMethod_INDEX_METHOD_BTREE       IndexMethod = 1
+	IndexMethod_INDEX_METHOD_GIN         IndexMethod = 2
+	IndexMethod_INDEX_METHOD_GIST        IndexMethod = 3
+	IndexMethod_INDEX_METHOD_HASH        IndexMethod = 4
+	IndexMethod_INDEX_METHOD_BRIN        IndexMethod = 5
+)
+
+// Enum value maps for IndexMethod.
+var (
+	IndexMethod_name = map[int32]string{
+		0: "INDEX_METHOD_UNSPECIFIED",
+		1: "INDEX_METHOD_BTREE",
+		2: "INDEX_METHOD_GIN",
+		3: "INDEX_METHOD_GIST",
+		4: "INDEX_METHOD_HASH",
+		5: "INDEX_METHOD_BRIN",
+	}
+	IndexMethod_value = map[string]int32{
+		"INDEX_METHOD_UNSPECIFIED": 0,
+		"INDEX_METHOD_BTREE":       1,
+		"INDEX_METHOD_GIN":         2,
+		"INDEX_METHOD_GIST":        3,
+		"INDEX_METHOD_HASH":        4,
+		"INDEX_METHOD_BRIN":        5,
+	}
+)
+
+func (x IndexMethod) Enum() *IndexMethod {
+	p := new(IndexMethod)
+	*p = x
+	return p
+}
+
+func (x IndexMethod) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (IndexMethod) Descriptor() protoreflect.EnumDescriptor {
+	return file_sqlc_sqlc_proto_enumTypes[2].Descriptor()
+}
+
+func (IndexMethod) Type() protoreflect.EnumType {
+	return &file_sqlc_sqlc_proto_enumTypes[2]
+}
+
+func (x IndexMethod) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use IndexMethod.Descriptor instead.
+func (IndexMethod) EnumDescriptor() ([]byte, []int) {
+	return file_sqlc_sqlc_proto_rawDescGZIP(), []int{2}
+}
+
+type EnumStorage int32
+
+const (
+	EnumStorage_ENUM_STORAGE_UNSPECIFIED EnumStorage = 0
+	// A native PostgreSQL enum type holding the value names.
+	EnumStorage_ENUM_STORAGE_NATIVE EnumStorage = 1
+	// A TEXT column holding the value name, with a CHECK listing the names.
+	EnumStorage_ENUM_STORAGE_TEXT EnumStorage = 2
+	// A SMALLINT column holding the value number, with a CHECK listing the
+	// numbers.
+	EnumStorage_ENUM_STORAGE_SMALLINT EnumStorage = 3
+	// A TEXT column holding the value name, referencing a lookup table seeded
+	// with the enum values.
+	EnumStorage_ENUM_STORAGE_TABLE EnumStorage = 4
+)
+
+// Enum value maps for EnumStorage.
+var (
+	EnumStorage_name = map[int32]string{
+		0: "ENUM_STORAGE_UNSPECIFIED",
+		1: "ENUM_STORAGE_NATIVE",
+		2: "ENUM_STORAGE_TEXT",
+		3: "ENUM_STORAGE_SMALLINT",
+		4: "ENUM_STORAGE_TABLE",
+	}
+	EnumStorage_value = map[string]int32{
+		"ENUM_STORAGE_UNSPECIFIED": 0,
+		"ENUM_STORAGE_NATIVE":      1,
+		"ENUM_STORAGE_TEXT":        2,
+		"ENUM_STORAGE_SMALLINT":    3,
+		"ENUM_STORAGE_TABLE":       4,
+	}
+)
+
+func (x EnumStorage) Enum() *EnumStorage {
+	p := new(EnumStorage)
+	*p = x
+	return p
+}
+
+func (x EnumStorage) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EnumStorage) Descriptor() protoreflect.EnumDescriptor {
+	return file_sqlc_sqlc_proto_enumTypes[3].Descriptor()
+}
+
+func (EnumStorage) Type() protoreflect.EnumType {
+	return &file_sqlc_sqlc_proto_enumTypes[3]
+}
+
+func (x EnumStorage) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EnumStorage.Descriptor instead.
+func (EnumStorage) EnumDescriptor() ([]byte, []int) {
+	return file_sqlc_sqlc_proto_rawDescGZIP(), []int{3}
+}
+
 type FieldConstraints struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Primary       bool                   `protobuf:"varint,1,opt,name=primary,proto3" json:"primary,omitempty"`
-	Unique        bool                   `protobuf:"varint,2,opt,name=unique,proto3" json:"unique,omitempty"`
-	References    string                 `protobuf:"bytes,3,opt,name=references,proto3" json:"references,omitempty"`
-	Default       string                 `protobuf:"bytes,4,opt,name=default,proto3" json:"default,omitempty"`
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Primary    bool                   `protobuf:"varint,1,opt,name=primary,proto3" json:"primary,omitempty"`
+	Unique     bool                   `protobuf:"varint,2,opt,name=unique,proto3" json:"unique,omitempty"`
+	References string                 `protobuf:"bytes,3,opt,name=references,proto3" json:"references,omitempty"`
+	Default    string                 `protobuf:"bytes,4,opt,name=default,proto3" json:"default,omitempty"`
+	// Index creates an index on this column. Its columns are ignored.
+	Index *Index `protobuf:"bytes,5,opt,name=index,proto3" json:"index,omitempty"`
+	// OnDelete is the action taken on this column when the row it references
+	// is deleted. Defaults to NO ACTION.
+	OnDelete ForeignKeyAction `protobuf:"varint,6,opt,name=on_delete,json=onDelete,proto3,enum=sqlc.ForeignKeyAction" json:"on_delete,omitempty"`
+	// OnUpdate is the action taken on this column when the row it references
+	// has its key updated. Defaults to NO ACTION.
+	OnUpdate ForeignKeyAction `protobuf:"varint,7,opt,name=on_update,json=onUpdate,proto3,enum=sqlc.ForeignKeyAction" json:"on_update,omitempty"`
+	// Deferrable makes the foreign key DEFERRABLE INITIALLY DEFERRED, so it is
+	// only checked when the transaction commits.
+	Deferrable bool `protobuf:"varint,8,opt,name=deferrable,proto3" json:"deferrable,omitempty"`
+	// MapStorage selects how a map field is stored. Defaults to JSONB.
+	MapStorage MapStorage `protobuf:"varint,9,opt,name=map_storage,json=mapStorage,proto3,enum=sqlc.MapStorage" json:"map_storage,omitempty"`
+	// Embed flattens a message field into columns of the parent table, named
+	// after the field and the nested field (address_street). Nested options
+	// apply to the flattened columns.
+	Embed bool `protobuf:"varint,10,opt,name=embed,proto3" json:"embed,omitempty"`
+	// ChildTable stores a repeated message field in a child table with one row
+	// per element, keyed by the parent primary key and the element position.
+	ChildTable bool `protobuf:"varint,11,opt,name=child_table,json=childTable,proto3" json:"child_table,omitempty"`
+	// AutoIncrement generates the values of an integer primary key on insert,
+	// as a SERIAL or BIGSERIAL column, or AUTO_INCREMENT in MySQL.
+	AutoIncrement bool `protobuf:"varint,12,opt,name=auto_increment,json=autoIncrement,proto3" json:"auto_increment,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -94,6 +350,418 @@ func (x *FieldConstraints) GetDefault() string {
 	return ""
 }
 
+func (x *FieldConstraints) GetIndex() *Index {
+	if x != nil {
+		return x.Index
+	}
+	return nil
+}
+
+func (x *FieldConstraints) GetOnDelete() ForeignKeyAction {
+	if x != nil {
+		return x.OnDelete
+	}
+	return ForeignKeyAction_FOREIGN_KEY_ACTION_UNSPECIFIED
+}
+
+func (x *FieldConstraints) GetOnUpdate() ForeignKeyAction {
+	if x != nil {
+		return x.OnUpdate
+	}
+	return ForeignKeyAction_FOREIGN_KEY_ACTION_UNSPECIFIED
+}
+
+func (x *FieldConstraints) GetDeferrable() bool {
+	if x != nil {
+		return x.Deferrable
+	}
+	return false
+}
+
+func (x *FieldConstraints) GetMapStorage() MapStorage {
+	if x != nil {
+		return x.MapStorage
+	}
+	return MapStorage_MAP_STORAGE_UNSPECIFIED
+}
+
+func (x *FieldConstraints) GetEmbed() bool {
+	if x != nil {
+		return x.Embed
+	}
+	return false
+}
+
+func (x *FieldConstraints) GetChildTable() bool {
+	if x != nil {
+		return x.ChildTable
+	}
+	return false
+}
+
+func (x *FieldConstraints) GetAutoIncrement() bool {
+	if x != nil {
+		return x.AutoIncrement
+	}
+	return false
+}
+
+type TableOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name overrides the table name, which defaults to the message name.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Skip excludes the message from the generated schema and queries.
+	Skip bool `protobuf:"varint,2,opt,name=skip,proto3" json:"skip,omitempty"`
+	// Include opts the message in when the plugin runs with
+	// only_annotated=true. It has no effect otherwise.
+	Include bool `protobuf:"varint,3,opt,name=include,proto3" json:"include,omitempty"`
+	// PrimaryKey lists the columns of a composite primary key. It cannot be
+	// combined with (sqlc.field).primary.
+	PrimaryKey []string `protobuf:"bytes,4,rep,name=primary_key,json=primaryKey,proto3" json:"primary_key,omitempty"`
+	// Unique declares multi-column unique constraints.
+	Unique []*UniqueConstraint `protobuf:"bytes,5,rep,name=unique,proto3" json:"unique,omitempty"`
+	// Index declares indexes that span several columns or an expression.
+	Index         []*Index `protobuf:"bytes,6,rep,name=index,proto3" json:"index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TableOptions) Reset() {
+	*x = TableOptions{}
+	mi := &file_sqlc_sqlc_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TableOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TableOptions) ProtoMessage() {}
+
+func (x *TableOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlc_sqlc_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TableOptions.ProtoReflect.Descriptor instead.
+func (*TableOptions) Descriptor() ([]byte, []int) {
+	return file_sqlc_sqlc_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TableOptions) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *TableOptions) GetSkip() bool {
+	if x != nil {
+		return x.Skip
+	}
+	return false
+}
+
+func (x *TableOptions) GetInclude() bool {
+	if x != nil {
+		return x.Include
+	}
+	return false
+}
+
+func (x *TableOptions) GetPrimaryKey() []string {
+	if x != nil {
+		return x.PrimaryKey
+	}
+	return nil
+}
+
+func (x *TableOptions) GetUnique() []*UniqueConstraint {
+	if x != nil {
+		return x.Unique
+	}
+	return nil
+}
+
+func (x *TableOptions) GetIndex() []*Index {
+	if x != nil {
+		return x.Index
+	}
+	return nil
+}
+
+type UniqueConstraint struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Columns       []string               `protobuf:"bytes,1,rep,name=columns,proto3" json:"columns,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UniqueConstraint) Reset() {
+	*x = UniqueConstraint{}
+	mi := &file_sqlc_sqlc_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UniqueConstraint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UniqueConstraint) ProtoMessage() {}
+
+func (x *UniqueConstraint) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlc_sqlc_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UniqueConstraint.ProtoReflect.Descriptor instead.
+func (*UniqueConstraint) Descriptor() ([]byte, []int) {
+	return file_sqlc_sqlc_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *UniqueConstraint) GetColumns() []string {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+type Index struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name overrides the generated name, which is derived from the table name
+	// and the indexed columns or expression.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Columns lists the indexed columns, in order.
+	Columns []string `protobuf:"bytes,2,rep,name=columns,proto3" json:"columns,omitempty"`
+	// Expression indexes the result of an SQL expression instead of columns,
+	// for example "lower(email)".
+	Expression string      `protobuf:"bytes,3,opt,name=expression,proto3" json:"expression,omitempty"`
+	Unique     bool        `protobuf:"varint,4,opt,name=unique,proto3" json:"unique,omitempty"`
+	Method     IndexMethod `protobuf:"varint,5,opt,name=method,proto3,enum=sqlc.IndexMethod" json:"method,omitempty"`
+	// Where turns the index into a partial index over the rows matching the
+	// predicate.
+	Where string `protobuf:"bytes,6,opt,name=where,proto3" json:"where,omitempty"`
+	// Include lists non-key columns stored in the index.
+	Include       []string `protobuf:"bytes,7,rep,name=include,proto3" json:"include,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Index) Reset() {
+	*x = Index{}
+	mi := &file_sqlc_sqlc_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Index) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Index) ProtoMessage() {}
+
+func (x *Index) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlc_sqlc_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Index.ProtoReflect.Descriptor instead.
+func (*Index) Descriptor() ([]byte, []int) {
+	return file_sqlc_sqlc_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Index) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Index) GetColumns() []string {
+	if x != nil {
+		return x.Columns
+	}
+	return nil
+}
+
+func (x *Index) GetExpression() string {
+	if x != nil {
+		return x.Expression
+	}
+	return ""
+}
+
+func (x *Index) GetUnique() bool {
+	if x != nil {
+		return x.Unique
+	}
+	return false
+}
+
+func (x *Index) GetMethod() IndexMethod {
+	if x != nil {
+		return x.Method
+	}
+	return IndexMethod_INDEX_METHOD_UNSPECIFIED
+}
+
+func (x *Index) GetWhere() string {
+	if x != nil {
+		return x.Where
+	}
+	return ""
+}
+
+func (x *Index) GetInclude() []string {
+	if x != nil {
+		return x.Include
+	}
+	return nil
+}
+
+type EnumOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Storage selects how columns of this enum type are stored.
+	Storage EnumStorage `protobuf:"varint,1,opt,name=storage,proto3,enum=sqlc.EnumStorage" json:"storage,omitempty"`
+	// StripPrefix removes the enum name prefix from the stored value names, so
+	// BOOK_TYPE_FICTION in BookType becomes FICTION.
+	StripPrefix bool `protobuf:"varint,2,opt,name=strip_prefix,json=stripPrefix,proto3" json:"strip_prefix,omitempty"`
+	// Lowercase stores the value names in lower case.
+	Lowercase bool `protobuf:"varint,3,opt,name=lowercase,proto3" json:"lowercase,omitempty"`
+	// OmitUnspecified leaves the zero value out of the stored values. Columns
+	// store it as NULL instead.
+	OmitUnspecified bool `protobuf:"varint,4,opt,name=omit_unspecified,json=omitUnspecified,proto3" json:"omit_unspecified,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *EnumOptions) Reset() {
+	*x = EnumOptions{}
+	mi := &file_sqlc_sqlc_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnumOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnumOptions) ProtoMessage() {}
+
+func (x *EnumOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlc_sqlc_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnumOptions.ProtoReflect.Descriptor instead.
+func (*EnumOptions) Descriptor() ([]byte, []int) {
+	return file_sqlc_sqlc_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EnumOptions) GetStorage() EnumStorage {
+	if x != nil {
+		return x.Storage
+	}
+	return EnumStorage_ENUM_STORAGE_UNSPECIFIED
+}
+
+func (x *EnumOptions) GetStripPrefix() bool {
+	if x != nil {
+		return x.StripPrefix
+	}
+	return false
+}
+
+func (x *EnumOptions) GetLowercase() bool {
+	if x != nil {
+		return x.Lowercase
+	}
+	return false
+}
+
+func (x *EnumOptions) GetOmitUnspecified() bool {
+	if x != nil {
+		return x.OmitUnspecified
+	}
+	return false
+}
+
+type EnumValueOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name overrides the stored name of the value, ignoring the strip_prefix
+	// and lowercase options of the enum.
+	Name          string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnumValueOptions) Reset() {
+	*x = EnumValueOptions{}
+	mi := &file_sqlc_sqlc_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnumValueOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnumValueOptions) ProtoMessage() {}
+
+func (x *EnumValueOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_sqlc_sqlc_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnumValueOptions.ProtoReflect.Descriptor instead.
+func (*EnumValueOptions) Descriptor() ([]byte, []int) {
+	return file_sqlc_sqlc_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *EnumValueOptions) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
 var file_sqlc_sqlc_proto_extTypes = []protoimpl.ExtensionInfo{
 	{
 		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
@@ -103,6 +771,30 @@ var file_sqlc_sqlc_proto_extTypes = []protoimpl.ExtensionInfo{
 		Tag:           "bytes,5001,opt,name=field",
 		Filename:      "sqlc/sqlc.proto",
 	},
+	{
+		ExtendedType:  (*descriptorpb.MessageOptions)(nil),
+		ExtensionType: (*TableOptions)(nil),
+		Field:         5001,
+		Name:          "sqlc.table",
+		Tag:           "bytes,5001,opt,name=table",
+		Filename:      "sqlc/sqlc.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.EnumOptions)(nil),
+		ExtensionType: (*EnumOptions)(nil),
+		Field:         5001,
+		Name:          "sqlc.enum",
+		Tag:           "bytes,5001,opt,name=enum",
+		Filename:      "sqlc/sqlc.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.EnumValueOptions)(nil),
+		ExtensionType: (*EnumValueOptions)(nil),
+		Field:         5001,
+		Name:          "sqlc.enum_value",
+		Tag:           "bytes,5001,opt,name=enum_value",
+		Filename:      "sqlc/sqlc.proto",
+	},
 }
 
 // Extension fields to descriptorpb.FieldOptions.
@@ -114,19 +806,115 @@ var (
 	E_Field = &file_sqlc_sqlc_proto_extTypes[0]
 )
 
+// Extension fields to descriptorpb.MessageOptions.
+var (
+	// Table configures the table generated for this message. By default, every
+	// top-level message becomes a table named after the message.
+	//
+	// optional sqlc.TableOptions table = 5001;
+	E_Table = &file_sqlc_sqlc_proto_extTypes[1]
+)
+
+// Extension fields to descriptorpb.EnumOptions.
+var (
+	// Enum configures the storage of this enum. By default, it follows the
+	// enum_storage plugin option.
+	//
+	// optional sqlc.EnumOptions enum = 5001;
+	E_Enum = &file_sqlc_sqlc_proto_extTypes[2]
+)
+
+// Extension fields to descriptorpb.EnumValueOptions.
+var (
+	// EnumValue configures the storage of this enum value.
+	//
+	// optional sqlc.EnumValueOptions enum_value = 5001;
+	E_EnumValue = &file_sqlc_sqlc_proto_extTypes[3]
+)
+
 var File_sqlc_sqlc_proto protoreflect.FileDescriptor
 
 const file_sqlc_sqlc_proto_rawDesc = "" +
 	"\n" +
-	"\x0fsqlc/sqlc.proto\x12\x04sqlc\x1a google/protobuf/descriptor.proto\"~\n" +
+	"\x0fsqlc/sqlc.proto\x12\x04sqlc\x1a google/protobuf/descriptor.proto\"\xbc\x03\n" +
 	"\x10FieldConstraints\x12\x18\n" +
 	"\aprimary\x18\x01 \x01(\bR\aprimary\x12\x16\n" +
 	"\x06unique\x18\x02 \x01(\bR\x06unique\x12\x1e\n" +
 	"\n" +
 	"references\x18\x03 \x01(\tR\n" +
 	"references\x12\x18\n" +
-	"\adefault\x18\x04 \x01(\tR\adefault:O\n" +
-	"\x05field\x12\x1d.google.protobuf.FieldOptions\x18\x89' \x01(\v2\x16.sqlc.FieldConstraintsR\x05field\x88\x01\x01BZ\n" +
+	"\adefault\x18\x04 \x01(\tR\adefault\x12!\n" +
+	"\x05index\x18\x05 \x01(\v2\v.sqlc.IndexR\x05index\x123\n" +
+	"\ton_delete\x18\x06 \x01(\x0e2\x16.sqlc.ForeignKeyActionR\bonDelete\x123\n" +
+	"\ton_update\x18\a \x01(\x0e2\x16.sqlc.ForeignKeyActionR\bonUpdate\x12\x1e\n" +
+	"\n" +
+	"deferrable\x18\b \x01(\bR\n" +
+	"deferrable\x121\n" +
+	"\vmap_storage\x18\t \x01(\x0e2\x10.sqlc.MapStorageR\n" +
+	"mapStorage\x12\x14\n" +
+	"\x05embed\x18\n" +
+	" \x01(\bR\x05embed\x12\x1f\n" +
+	"\vchild_table\x18\v \x01(\bR\n" +
+	"childTable\x12%\n" +
+	"\x0eauto_increment\x18\f \x01(\bR\rautoIncrement\"\xc4\x01\n" +
+	"\fTableOptions\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04skip\x18\x02 \x01(\bR\x04skip\x12\x18\n" +
+	"\ainclude\x18\x03 \x01(\bR\ainclude\x12\x1f\n" +
+	"\vprimary_key\x18\x04 \x03(\tR\n" +
+	"primaryKey\x12.\n" +
+	"\x06unique\x18\x05 \x03(\v2\x16.sqlc.UniqueConstraintR\x06unique\x12!\n" +
+	"\x05index\x18\x06 \x03(\v2\v.sqlc.IndexR\x05index\",\n" +
+	"\x10UniqueConstraint\x12\x18\n" +
+	"\acolumns\x18\x01 \x03(\tR\acolumns\"\xc8\x01\n" +
+	"\x05Index\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\acolumns\x18\x02 \x03(\tR\acolumns\x12\x1e\n" +
+	"\n" +
+	"expression\x18\x03 \x01(\tR\n" +
+	"expression\x12\x16\n" +
+	"\x06unique\x18\x04 \x01(\bR\x06unique\x12)\n" +
+	"\x06method\x18\x05 \x01(\x0e2\x11.sqlc.IndexMethodR\x06method\x12\x14\n" +
+	"\x05where\x18\x06 \x01(\tR\x05where\x12\x18\n" +
+	"\ainclude\x18\a \x03(\tR\ainclude\"\xa6\x01\n" +
+	"\vEnumOptions\x12+\n" +
+	"\astorage\x18\x01 \x01(\x0e2\x11.sqlc.EnumStorageR\astorage\x12!\n" +
+	"\fstrip_prefix\x18\x02 \x01(\bR\vstripPrefix\x12\x1c\n" +
+	"\tlowercase\x18\x03 \x01(\bR\tlowercase\x12)\n" +
+	"\x10omit_unspecified\x18\x04 \x01(\bR\x0fomitUnspecified\"&\n" +
+	"\x10EnumValueOptions\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name*o\n" +
+	"\n" +
+	"MapStorage\x12\x1b\n" +
+	"\x17MAP_STORAGE_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11MAP_STORAGE_JSONB\x10\x01\x12\x16\n" +
+	"\x12MAP_STORAGE_HSTORE\x10\x02\x12\x15\n" +
+	"\x11MAP_STORAGE_TABLE\x10\x03*\xde\x01\n" +
+	"\x10ForeignKeyAction\x12\"\n" +
+	"\x1eFOREIGN_KEY_ACTION_UNSPECIFIED\x10\x00\x12 \n" +
+	"\x1cFOREIGN_KEY_ACTION_NO_ACTION\x10\x01\x12\x1f\n" +
+	"\x1bFOREIGN_KEY_ACTION_RESTRICT\x10\x02\x12\x1e\n" +
+	"\x1aFOREIGN_KEY_ACTION_CASCADE\x10\x03\x12\x1f\n" +
+	"\x1bFOREIGN_KEY_ACTION_SET_NULL\x10\x04\x12\"\n" +
+	"\x1eFOREIGN_KEY_ACTION_SET_DEFAULT\x10\x05*\x9e\x01\n" +
+	"\vIndexMethod\x12\x1c\n" +
+	"\x18INDEX_METHOD_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12INDEX_METHOD_BTREE\x10\x01\x12\x14\n" +
+	"\x10INDEX_METHOD_GIN\x10\x02\x12\x15\n" +
+	"\x11INDEX_METHOD_GIST\x10\x03\x12\x15\n" +
+	"\x11INDEX_METHOD_HASH\x10\x04\x12\x15\n" +
+	"\x11INDEX_METHOD_BRIN\x10\x05*\x8e\x01\n" +
+	"\vEnumStorage\x12\x1c\n" +
+	"\x18ENUM_STORAGE_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13ENUM_STORAGE_NATIVE\x10\x01\x12\x15\n" +
+	"\x11ENUM_STORAGE_TEXT\x10\x02\x12\x19\n" +
+	"\x15ENUM_STORAGE_SMALLINT\x10\x03\x12\x16\n" +
+	"\x12ENUM_STORAGE_TABLE\x10\x04:O\n" +
+	"\x05field\x12\x1d.google.protobuf.FieldOptions\x18\x89' \x01(\v2\x16.sqlc.FieldConstraintsR\x05field\x88\x01\x01:M\n" +
+	"\x05table\x12\x1f.google.protobuf.MessageOptions\x18\x89' \x01(\v2\x12.sqlc.TableOptionsR\x05table\x88\x01\x01:G\n" +
+	"\x04enum\x12\x1c.google.protobuf.EnumOptions\x18\x89' \x01(\v2\x11.sqlc.EnumOptionsR\x04enum\x88\x01\x01:\\\n" +
+	"\n" +
+	"enum_value\x12!.google.protobuf.EnumValueOptions\x18\x89' \x01(\v2\x16.sqlc.EnumValueOptionsR\tenumValue\x88\x01\x01BZ\n" +
 	"\bcom.sqlcB\tSqlcProtoP\x01Z\x13internal/gen/sqlcpb\xa2\x02\x03SXX\xaa\x02\x04Sqlc\xca\x02\x04Sqlc\xe2\x02\x10Sqlc\\GPBMetadata\xea\x02\x04Sqlcb\x06proto3"
 
 var (
@@ -141,19 +929,46 @@ func file_sqlc_sqlc_proto_rawDescGZIP() []byte {
 	return file_sqlc_sqlc_proto_rawDescData
 }
 
-var file_sqlc_sqlc_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_sqlc_sqlc_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_sqlc_sqlc_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
 var file_sqlc_sqlc_proto_goTypes = []any{
-	(*FieldConstraints)(nil),          // 0: sqlc.FieldConstraints
-	(*descriptorpb.FieldOptions)(nil), // 1: google.protobuf.FieldOptions
+	(MapStorage)(0),                       // 0: sqlc.MapStorage
+	(ForeignKeyAction)(0),                 // 1: sqlc.ForeignKeyAction
+	(IndexMethod)(0),                      // 2: sqlc.IndexMethod
+	(EnumStorage)(0),                      // 3: sqlc.EnumStorage
+	(*FieldConstraints)(nil),              // 4: sqlc.FieldConstraints
+	(*TableOptions)(nil),                  // 5: sqlc.TableOptions
+	(*UniqueConstraint)(nil),              // 6: sqlc.UniqueConstraint
+	(*Index)(nil),                         // 7: sqlc.Index
+	(*EnumOptions)(nil),                   // 8: sqlc.EnumOptions
+	(*EnumValueOptions)(nil),              // 9: sqlc.EnumValueOptions
+	(*descriptorpb.FieldOptions)(nil),     // 10: google.protobuf.FieldOptions
+	(*descriptorpb.MessageOptions)(nil),   // 11: google.protobuf.MessageOptions
+	(*descriptorpb.EnumOptions)(nil),      // 12: google.protobuf.EnumOptions
+	(*descriptorpb.EnumValueOptions)(nil), // 13: google.protobuf.EnumValueOptions
 }
 var file_sqlc_sqlc_proto_depIdxs = []int32{
-	1, // 0: sqlc.field:extendee -> google.protobuf.FieldOptions
-	0, // 1: sqlc.field:type_name -> sqlc.FieldConstraints
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	1, // [1:2] is the sub-list for extension type_name
-	0, // [0:1] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	7,  // 0: sqlc.FieldConstraints.index:type_name -> sqlc.Index
+	1,  // 1: sqlc.FieldConstraints.on_delete:type_name -> sqlc.ForeignKeyAction
+	1,  // 2: sqlc.FieldConstraints.on_update:type_name -> sqlc.ForeignKeyAction
+	0,  // 3: sqlc.FieldConstraints.map_storage:type_name -> sqlc.MapStorage
+	6,  // 4: sqlc.TableOptions.unique:type_name -> sqlc.UniqueConstraint
+	7,  // 5: sqlc.TableOptions.index:type_name -> sqlc.Index
+	2,  // 6: sqlc.Index.method:type_name -> sqlc.IndexMethod
+	3,  // 7: sqlc.EnumOptions.storage:type_name -> sqlc.EnumStorage
+	10, // 8: sqlc.field:extendee -> google.protobuf.FieldOptions
+	11, // 9: sqlc.table:extendee -> google.protobuf.MessageOptions
+	12, // 10: sqlc.enum:extendee -> google.protobuf.EnumOptions
+	13, // 11: sqlc.enum_value:extendee -> google.protobuf.EnumValueOptions
+	4,  // 12: sqlc.field:type_name -> sqlc.FieldConstraints
+	5,  // 13: sqlc.table:type_name -> sqlc.TableOptions
+	8,  // 14: sqlc.enum:type_name -> sqlc.EnumOptions
+	9,  // 15: sqlc.enum_value:type_name -> sqlc.EnumValueOptions
+	16, // [16:16] is the sub-list for method output_type
+	16, // [16:16] is the sub-list for method input_type
+	12, // [12:16] is the sub-list for extension type_name
+	8,  // [8:12] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_sqlc_sqlc_proto_init() }
@@ -166,13 +981,14 @@ func file_sqlc_sqlc_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sqlc_sqlc_proto_rawDesc), len(file_sqlc_sqlc_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   1,
-			NumExtensions: 1,
+			NumEnums:      4,
+			NumMessages:   6,
+			NumExtensions: 4,
 			NumServices:   0,
 		},
 		GoTypes:           file_sqlc_sqlc_proto_goTypes,
 		DependencyIndexes: file_sqlc_sqlc_proto_depIdxs,
+		EnumInfos:         file_sqlc_sqlc_proto_enumTypes,
 		MessageInfos:      file_sqlc_sqlc_proto_msgTypes,
 		ExtensionInfos:    file_sqlc_sqlc_proto_extTypes,
 	}.Build()
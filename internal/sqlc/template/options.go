@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package template
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/core"
+)
+
+// DefaultSchemaFile is the name of the schema file when none is set.
+const DefaultSchemaFile = "schema.sql"
+
+// SingleQueryFile is the name of the query file of the single layout.
+const SingleQueryFile = "queries.sql"
+
+// Layout selects how generated queries are split into files.
+type Layout string
+
+const (
+	// LayoutPerFile writes one query file per proto file, next to its Go
+	// package.
+	LayoutPerFile Layout = "per_file"
+	// LayoutSingle writes the queries of every proto file to a single file
+	// next to the schema.
+	LayoutSingle Layout = "single"
+)
+
+// QueryKind names a kind of generated query.
+type QueryKind string
+
+const (
+	QueryGet    QueryKind = "get"
+	QueryList   QueryKind = "list"
+	QueryCreate QueryKind = "create"
+	QueryUpdate QueryKind = "update"
+	QueryDelete QueryKind = "delete"
+)
+
+// Options holds the plugin parameters that drive code generation.
+type Options struct {
+	// OnlyAnnotated restricts table generation to messages that opt in with
+	// the (sqlc.table).include option.
+	OnlyAnnotated bool
+	// FieldPresence makes fields without presence tracking NOT NULL, with
+	// their zero value as default, so that only fields with explicit presence
+	// are nullable.
+	FieldPresence bool
+	// EnumStorage selects how enums without a (sqlc.enum).storage option are
+	// stored. Defaults to native enum types.
+	EnumStorage core.EnumStorage
+	// NestedMessages generates tables for messages nested in other messages
+	// too, named after the qualified message name.
+	NestedMessages bool
+	// Naming selects how protobuf names are turned into SQL identifiers.
+	// Defaults to preserving them.
+	Naming core.NamingStrategy
+	// Dialect selects the SQL dialect of the output. Defaults to PostgreSQL.
+	Dialect core.Dialect
+	// Layout selects how queries are split into files. Defaults to one file
+	// per proto file.
+	Layout Layout
+	// Queries lists the kinds of queries to generate. Defaults to all of them.
+	Queries []QueryKind
+	// SchemaFile is the name of the schema file. Defaults to schema.sql.
+	SchemaFile string
+	// LogLevel is the minimum level of the messages logged while generating.
+	LogLevel slog.Level
+	// Templates is a directory of templates that override or extend the
+	// embedded ones.
+	Templates string
+}
+
+// SQLDialect returns the SQL dialect of the output, PostgreSQL unless set.
+func (o Options) SQLDialect() core.Dialect {
+	if o.Dialect == nil {
+		return core.PostgreSQL{}
+	}
+
+	return o.Dialect
+}
+
+// Generates reports whether queries of the given kind are generated.
+func (o Options) Generates(kind QueryKind) bool {
+	return len(o.Queries) == 0 || slices.Contains(o.Queries, kind)
+}
+
+// Set parses a plugin parameter into the options. It is meant to be used as
+// the protogen ParamFunc. Boolean parameters given without a value are set to
+// true, and unknown parameters are rejected.
+func (o *Options) Set(name, value string) error {
+	params := parameters()
+
+	i := slices.IndexFunc(params, func(p parameter) bool { return p.name == name })
+	if i < 0 {
+		names := make([]string, 0, len(params))
+		for _, p := range params {
+			names = append(names, p.name)
+		}
+
+		return fmt.Errorf(
+			"unknown parameter %q, supported parameters are %s",
+			name, strings.Join(names, ", "),
+		)
+	}
+
+	if err := params[i].set(o, value); err != nil {
+		return fmt.Errorf("invalid value %q for parameter %s: %w", value, name, err)
+	}
+
+	return nil
+}
+
+// parameter describes a plugin parameter and how it is parsed.
+type parameter struct {
+	name string
+	set  func(o *Options, value string) error
+}
+
+func parameters() []parameter {
+	return []parameter{
+		{"only_annotated", func(o *Options, value string) error {
+			return parseBool(&o.OnlyAnnotated, value)
+		}},
+		{"field_presence", func(o *Options, value string) error {
+			return parseBool(&o.FieldPresence, value)
+		}},
+		{"nested_messages", func(o *Options, value string) error {
+			return parseBool(&o.NestedMessages, value)
+		}},
+		{"enum_storage", func(o *Options, value string) error {
+			return parseOneOf(&o.EnumStorage, value,
+				core.EnumStorageNative, core.EnumStorageText,
+				core.EnumStorageSmallInt, core.EnumStorageTable)
+		}},
+		{"naming", func(o *Options, value string) error {
+			return parseOneOf(&o.Naming, value,
+				core.NamingPreserve, core.NamingSnakeCase, core.NamingPlural)
+		}},
+		{"dialect", func(o *Options, value string) error {
+			dialects := core.Dialects()
+
+			names := make([]string, 0, len(dialects))
+			for _, dialect := range dialects {
+				names = append(names, dialect.Name())
+			}
+
+			var name string
+			if err := parseOneOf(&name, value, names...); err != nil {
+				return err
+			}
+
+			o.Dialect = dialects[slices.Index(names, name)]
+
+			return nil
+		}},
+		{"layout", func(o *Options, value string) error {
+			return parseOneOf(&o.Layout, value, LayoutPerFile, LayoutSingle)
+		}},
+		{"queries", func(o *Options, value string) error {
+			var kind QueryKind
+			if err := parseOneOf(&kind, value,
+				QueryGet, QueryList, QueryCreate, QueryUpdate, QueryDelete); err != nil {
+				return err
+			}
+
+			if !slices.Contains(o.Queries, kind) {
+				o.Queries = append(o.Queries, kind)
+			}
+
+			return nil
+		}},
+		{"schema_file", func(o *Options, value string) error {
+			if value == "" {
+				return errors.New("want a file name")
+			}
+
+			o.SchemaFile = value
+
+			return nil
+		}},
+		{"log_level", func(o *Options, value string) error {
+			return o.LogLevel.UnmarshalText([]byte(value))
+		}},
+		{"templates", func(o *Options, value string) error {
+			if value == "" {
+				return errors.New("want a directory")
+			}
+
+			o.Templates = value
+
+			return nil
+		}},
+	}
+}
+
+// parseBool parses a boolean parameter, where an empty value means true.
+func parseBool(target *bool, value string) error {
+	if value == "" {
+		*target = true
+
+		return nil
+	}
+
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return errors.New("want true or false")
+	}
+
+	*target = v
+
+	return nil
+}
+
+// parseOneOf parses a parameter that takes one of the allowed values.
+func parseOneOf[T ~string](target *T, value string, allowed ...T) error {
+	if !slices.Contains(allowed, T(value)) {
+		names := make([]string, 0, len(allowed))
+		for _, a := range allowed {
+			names = append(names, string(a))
+		}
+
+		return fmt.Errorf("want one of %s", strings.Join(names, ", "))
+	}
+
+	*target = T(value)
+
+	return nil
+}
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2024 Pablo Jiménez Pascual <pablo@jimpas.me>
+//
+// SPDX-License-Identifier: BSD-3-Clause
+
+package template_test
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/core"
+	"github.com/pablojimpas/protoc-gen-sqlc/internal/sqlc/template"
+)
+
+func TestOptionsSet(t *testing.T) {
+	t.Parallel()
+
+	var opts template.Options
+
+	for _, param := range [][2]string{
+		{"only_annotated", ""},
+		{"field_presence", "false"},
+		{"enum_storage", "smallint"},
+		{"naming", "snake_case"},
+		{"dialect", "postgresql"},
+		{"layout", "single"},
+		{"queries", "get"},
+		{"queries", "list"},
+		{"queries", "get"},
+		{"schema_file", "db/schema.sql"},
+		{"log_level", "debug"},
+		{"templates", "sql/templates"},
+	} {
+		if err := opts.Set(param[0], param[1]); err != nil {
+			t.Fatalf("Set(%q, %q): %v", param[0], param[1], err)
+		}
+	}
+
+	want := template.Options{
+		OnlyAnnotated: true,
+		EnumStorage:   core.EnumStorageSmallInt,
+		Naming:        core.NamingSnakeCase,
+		Dialect:       core.PostgreSQL{},
+		Layout:        template.LayoutSingle,
+		Queries:       []template.QueryKind{template.QueryGet, template.QueryList},
+		SchemaFile:    "db/schema.sql",
+		LogLevel:      slog.LevelDebug,
+		Templates:     "sql/templates",
+	}
+
+	if !reflect.DeepEqual(opts, want) {
+		t.Errorf("options = %+v, want %+v", opts, want)
+	}
+}
+
+func TestOptionsSetErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name, value string
+		want        string
+	}{
+		{"unknown", "1", `unknown parameter "unknown", supported parameters are only_annotated,`},
+		{"only_annotated", "yes", `invalid value "yes" for parameter only_annotated: want true or false`},
+		{"naming", "camel", "want one of preserve, snake_case, plural"},
+		{"dialect", "sqlite", "want one of postgresql, mysql"},
+		{"queries", "upsert", "want one of get, list, create, update, delete"},
+		{"schema_file", "", "want a file name"},
+		{"log_level", "loud", `invalid value "loud" for parameter log_level`},
+	}
+
+	for _, tt := range tests {
+		var opts template.Options
+
+		err := opts.Set(tt.name, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Set(%q, %q) = %v, want error containing %q", tt.name, tt.value, err, tt.want)
+		}
+	}
+}
+
+func TestApplyCrudTemplateQueryKinds(t *testing.T) {
+	t.Parallel()
+
+	table := core.Table{
+		Name: "books",
+		Columns: []core.Column{
+			{Name: "id", Type: core.SerialType, NotNull: true},
+			{Name: "title", Type: core.TextType, NotNull: true},
+		},
+		Constraints: []core.Constraint{
+			{Type: core.PrimaryKeyConstraint, Columns: []string{"id"}},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	tmpl := template.New()
+
+	err := tmpl.ApplyCrud(&buf, &template.CrudParams{
+		GoName:     "Book",
+		PrimaryKey: table.PrimaryKey(),
+		Table:      table,
+		Options: template.Options{
+			Queries: []template.QueryKind{template.QueryGet, template.QueryDelete},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "\n-- name: GetBook :one\nSELECT * FROM books\nWHERE id = $1 LIMIT 1;\n" +
+		"\n-- name: DeleteBook :exec\nDELETE FROM books\nWHERE id = $1;\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
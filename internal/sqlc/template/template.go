@@ -6,7 +6,10 @@ package template
 
 import (
 	"embed"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"text/template"
 
 	"github.com/Masterminds/sprig/v3"
@@ -22,22 +25,143 @@ type Templates struct {
 	header *template.Template
 	schema *template.Template
 	crud   *template.Template
+	child  *template.Template
 }
 
-// New creates a new set of initialized templates.
+// New creates a new set of initialized templates from the embedded ones, for
+// PostgreSQL.
 func New() *Templates {
-	return &Templates{
-		header: parse("header.tmpl"),
-		schema: parse("schema.tmpl"),
-		crud:   parse("crud.tmpl"),
+	t, err := Load(Options{})
+	if err != nil {
+		panic(err)
 	}
+
+	return t
+}
+
+// Load creates a new set of templates for the dialect of the options from the
+// embedded ones, overridden and extended by the *.tmpl files of the Templates
+// directory if set. A file named after an embedded template replaces it,
+// while the define blocks of every file replace or add to the named
+// templates, such as "where" or the empty "schema_extra", "crud_extra" and
+// "child_extra" blocks.
+func Load(opts Options) (*Templates, error) {
+	overrides, err := readOverrides(opts.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	var t Templates
+
+	for _, entry := range []struct {
+		tmpl **template.Template
+		file string
+	}{
+		{&t.header, "header.tmpl"},
+		{&t.schema, "schema.tmpl"},
+		{&t.crud, "crud.tmpl"},
+		{&t.child, "child.tmpl"},
+	} {
+		if *entry.tmpl, err = parse(entry.file, overrides, opts.SQLDialect()); err != nil {
+			return nil, err
+		}
+	}
+
+	return &t, nil
+}
+
+// override is a user-supplied template file.
+type override struct {
+	path string
+	text string
+}
+
+// readOverrides reads the template files of dir in name order.
+func readOverrides(dir string) ([]override, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("reading templates: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("reading templates: %w", err)
+	}
+
+	overrides := make([]override, 0, len(paths))
+
+	for _, path := range paths {
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading templates: %w", err)
+		}
+
+		overrides = append(overrides, override{path: path, text: string(text)})
+	}
+
+	return overrides, nil
 }
 
-func parse(file string) *template.Template {
-	return template.Must(template.New(file).Funcs(sprig.TxtFuncMap()).ParseFS(files, file))
+// parse parses an embedded template followed by the overrides, which are
+// named after their path so that errors point at them. It returns the
+// override named after the embedded file if any, or the embedded template.
+func parse(file string, overrides []override, dialect core.Dialect) (*template.Template, error) {
+	text, err := files.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := template.New(file).
+		Funcs(sprig.TxtFuncMap()).
+		Funcs(funcs(dialect)).
+		Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+
+	entry := root
+
+	for _, o := range overrides {
+		tmpl, err := root.New(o.path).Parse(o.text)
+		if err != nil {
+			return nil, err
+		}
+
+		if filepath.Base(o.path) == file {
+			entry = tmpl
+		}
+	}
+
+	return entry, nil
 }
 
-type Options struct{}
+// funcs returns the template functions added on top of sprig, rendering SQL
+// in the given dialect.
+func funcs(dialect core.Dialect) template.FuncMap {
+	return template.FuncMap{
+		// dialect returns the dialect, to check the features it supports
+		"dialect": func() core.Dialect { return dialect },
+		// quoteIdent quotes an identifier when it is not safe to render as is
+		"quoteIdent": dialect.QuoteIdent,
+		// quoteIdents quotes a list of identifiers, to be joined afterwards
+		"quoteIdents": func(names []string) []string { return core.QuoteIdents(dialect, names) },
+		// quoteLiteral renders a string literal, such as an enum value name
+		"quoteLiteral": dialect.QuoteLiteral,
+		// columnType renders a column type
+		"columnType": dialect.ColumnType,
+		// stringType returns the type of strings up to the given length
+		"stringType": func(maxLen int) core.ColumnType { return dialect.StringType(uint64(maxLen)) },
+		// placeholder renders the nth query parameter, starting from 1, taking
+		// the int64 that sprig arithmetic returns
+		"placeholder": func(n int64) string { return dialect.Placeholder(int(n)) },
+		// upsert renders the clause that updates the listed columns of a row
+		// on key conflicts, or leaves it as is
+		"upsert": dialect.Upsert,
+	}
+}
 
 type HeaderParams struct {
 	Sources []string
@@ -51,10 +175,17 @@ type SchemaParams struct {
 
 type CrudParams struct {
 	GoName     string
-	PrimaryKey string
+	PrimaryKey []string
+	core.Table
+	Options
+}
+
+type ChildParams struct {
+	GoName    string
+	ParentKey []string
+	Key       string
 	core.Table
 	Options
-	HeaderParams
 }
 
 // ApplySchema applies the schema template with the provided parameters.
@@ -66,11 +197,19 @@ func (t *Templates) ApplySchema(w io.Writer, p *SchemaParams) error {
 	return t.schema.Execute(w, p)
 }
 
-// ApplyCrud applies the CRUD template with the provided parameters.
-func (t *Templates) ApplyCrud(w io.Writer, p *CrudParams) error {
-	if err := t.header.Execute(w, p.HeaderParams); err != nil {
-		return err
-	}
+// ApplyHeader applies the header template that starts every query file.
+func (t *Templates) ApplyHeader(w io.Writer, p *HeaderParams) error {
+	return t.header.Execute(w, p)
+}
 
+// ApplyCrud applies the CRUD template with the provided parameters. It emits
+// no header, as a query file holds the queries of several messages.
+func (t *Templates) ApplyCrud(w io.Writer, p *CrudParams) error {
 	return t.crud.Execute(w, p)
 }
+
+// ApplyChild applies the child table template with the provided parameters.
+// It emits no header, as child queries follow those of their parent.
+func (t *Templates) ApplyChild(w io.Writer, p *ChildParams) error {
+	return t.child.Execute(w, p)
+}
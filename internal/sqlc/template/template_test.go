@@ -6,6 +6,9 @@ package template_test
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/pablojimpas/protoc-gen-sqlc/internal/core"
@@ -38,9 +41,15 @@ func TestApplySchemaTemplate(t *testing.T) {
 				Constraints: []core.Constraint{
 					{Type: core.PrimaryKeyConstraint, Columns: []string{"id"}},
 					{
-						Type:       core.ForeignKeyConstraint,
-						Columns:    []string{"author_id"},
-						References: &core.Reference{Table: "authors", Columns: []string{"id"}},
+						Type:    core.ForeignKeyConstraint,
+						Columns: []string{"author_id"},
+						References: &core.Reference{
+							Table:      "authors",
+							Columns:    []string{"id"},
+							OnDelete:   core.ForeignKeyActionSetNull,
+							OnUpdate:   core.ForeignKeyActionCascade,
+							Deferrable: true,
+						},
 					},
 				},
 			},
@@ -58,6 +67,97 @@ func TestApplySchemaTemplate(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+
+	want := "FOREIGN KEY(author_id) REFERENCES authors(id) ON DELETE SET NULL ON UPDATE CASCADE " +
+		"DEFERRABLE INITIALLY DEFERRED\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("output does not contain %q:\n%s", want, buf.String())
+	}
+}
+
+func TestApplySchemaTemplateIndexes(t *testing.T) {
+	t.Parallel()
+
+	schema := core.Schema{
+		Tables: []core.Table{
+			{
+				Name: "users",
+				Columns: []core.Column{
+					{Name: "id", Type: core.IntegerType, NotNull: true},
+					{Name: "email", Type: core.TextType},
+					{Name: "deleted", Type: core.BooleanType},
+				},
+				Indexes: []core.Index{
+					{Name: "users_email_idx", Columns: []string{"email"}, Method: core.IndexMethodHash},
+					{
+						Name:       "users_lower_email_idx",
+						Expression: "lower(email)",
+						Unique:     true,
+						Where:      "NOT deleted",
+						Include:    []string{"id"},
+					},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	tmpl := template.New()
+
+	err := tmpl.ApplySchema(&buf, &template.SchemaParams{Schema: schema})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		");\nCREATE INDEX users_email_idx ON users USING hash (email);\n",
+		"CREATE UNIQUE INDEX users_lower_email_idx ON users ((lower(email))) " +
+			"INCLUDE (id) WHERE NOT deleted;\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestApplySchemaTemplateEnums(t *testing.T) {
+	t.Parallel()
+
+	// Renamed values may hold quotes
+	values := []core.EnumValue{{Name: "SIZE_S", Number: 1}, {Name: "size 'm'", Number: 2}}
+
+	schema := core.Schema{
+		Enums: []core.Enum{
+			{Name: "Kind", Values: values, Storage: core.EnumStorageNative},
+			{Name: "Color", Values: values, Storage: core.EnumStorageText},
+			{Name: "Size", Values: values, Storage: core.EnumStorageTable},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	tmpl := template.New()
+
+	err := tmpl.ApplySchema(&buf, &template.SchemaParams{Schema: schema})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"CREATE TYPE \"Kind\" AS ENUM (\n  'SIZE_S', \n  'size ''m'''\n);\n",
+		"CREATE TABLE \"Size\" (\n    name TEXT NOT NULL,\n    number SMALLINT NOT NULL,\n" +
+			"    PRIMARY KEY(name),\n    UNIQUE(number)\n);\n",
+		"INSERT INTO \"Size\" (name, number) VALUES\n  ('SIZE_S', 1),\n  ('size ''m''', 2);\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, buf.String())
+		}
+	}
+
+	if strings.Contains(buf.String(), "Color") {
+		t.Errorf("output declares the text enum Color:\n%s", buf.String())
+	}
 }
 
 func TestApplyCrudTemplate(t *testing.T) {
@@ -87,11 +187,10 @@ func TestApplyCrudTemplate(t *testing.T) {
 	err := tmpl.ApplyCrud(
 		&buf,
 		&template.CrudParams{
-			GoName:       "Book",
-			PrimaryKey:   "id",
-			Table:        table,
-			Options:      template.Options{},
-			HeaderParams: template.HeaderParams{},
+			GoName:     "Book",
+			PrimaryKey: []string{"id"},
+			Table:      table,
+			Options:    template.Options{},
 		},
 	)
 	if err != nil {
@@ -99,6 +198,266 @@ func TestApplyCrudTemplate(t *testing.T) {
 	}
 }
 
+func TestApplyCrudTemplateCompositeKey(t *testing.T) {
+	t.Parallel()
+
+	table := core.Table{
+		Name: "memberships",
+		Columns: []core.Column{
+			{Name: "group_id", Type: core.IntegerType, NotNull: true},
+			{Name: "user_id", Type: core.IntegerType, NotNull: true},
+			{Name: "role", Type: core.TextType},
+		},
+		Constraints: []core.Constraint{
+			{Type: core.PrimaryKeyConstraint, Columns: []string{"group_id", "user_id"}},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	tmpl := template.New()
+
+	err := tmpl.ApplyCrud(
+		&buf,
+		&template.CrudParams{
+			GoName:     "Membership",
+			PrimaryKey: table.PrimaryKey(),
+			Table:      table,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"WHERE group_id = $1 AND user_id = $2 LIMIT 1;",
+		"ORDER BY group_id, user_id;",
+		"UPDATE memberships SET\n  role = $3\nWHERE group_id = $1 AND user_id = $2",
+		"DELETE FROM memberships\nWHERE group_id = $1 AND user_id = $2;",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestApplyChildTemplate(t *testing.T) {
+	t.Parallel()
+
+	table := core.Table{
+		Name: "books_labels",
+		Columns: []core.Column{
+			{Name: "book_id", Type: core.BigIntType, NotNull: true},
+			{Name: "key", Type: core.TextType, NotNull: true},
+			{Name: "value", Type: core.TextType},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	tmpl := template.New()
+
+	err := tmpl.ApplyChild(&buf, &template.ChildParams{
+		GoName:    "BookLabels",
+		ParentKey: []string{"book_id"},
+		Key:       "key",
+		Table:     table,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"ON CONFLICT (book_id, key) DO UPDATE SET\n  value = EXCLUDED.value;",
+		"WHERE book_id = $1\nORDER BY key;",
+		"WHERE book_id = $1 AND key = $2;",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestApplyTemplatesQuoteIdentifiers(t *testing.T) {
+	t.Parallel()
+
+	table := core.Table{
+		Name: "Order",
+		Columns: []core.Column{
+			{Name: "id", Type: core.SerialType, NotNull: true},
+			{Name: "user", Type: core.TextType, NotNull: true},
+			{Name: "customerName", Type: core.TextType},
+		},
+		Constraints: []core.Constraint{
+			{Type: core.PrimaryKeyConstraint, Columns: []string{"id"}},
+			{Type: core.UniqueConstraint, Columns: []string{"user", "customerName"}},
+		},
+		Indexes: []core.Index{
+			{Name: "order_user_idx", Columns: []string{"user"}},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	tmpl := template.New()
+
+	err := tmpl.ApplySchema(&buf, &template.SchemaParams{Schema: core.Schema{Tables: []core.Table{table}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tmpl.ApplyCrud(&buf, &template.CrudParams{GoName: "Order", PrimaryKey: []string{"id"}, Table: table})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"CREATE TABLE \"Order\" (\n    id SERIAL NOT NULL,\n    \"user\" TEXT NOT NULL,\n" +
+			"    \"customerName\" TEXT,\n",
+		"UNIQUE(\"user\", \"customerName\")",
+		"CREATE INDEX order_user_idx ON \"Order\" (\"user\");",
+		"SELECT * FROM \"Order\"\nWHERE id = $1 LIMIT 1;",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestApplyTemplatesMySQL(t *testing.T) {
+	t.Parallel()
+
+	schema := core.Schema{
+		Extensions: []string{"hstore"},
+		Enums: []core.Enum{
+			{Name: "Kind", Values: []core.EnumValue{{Name: "KIND_BIG", Number: 1}}},
+			{
+				Name:    "Size",
+				Storage: core.EnumStorageTable,
+				Values:  []core.EnumValue{{Name: "SIZE_BIG", Number: 1}, {Name: `SIZE\XL`, Number: 2}},
+			},
+		},
+		Tables: []core.Table{{
+			Name: "Book",
+			Columns: []core.Column{
+				{Name: "id", Type: core.SerialType, NotNull: true},
+				{Name: "title", Type: core.TextType, NotNull: true},
+				{Name: "tags", Type: core.ColumnType(core.TextType + "[]")},
+				{Name: "kind", Type: "ENUM('KIND_BIG')"},
+			},
+			Constraints: []core.Constraint{
+				{Type: core.PrimaryKeyConstraint, Columns: []string{"id"}},
+			},
+		}},
+	}
+	labels := core.Table{
+		Name: "Book_labels",
+		Columns: []core.Column{
+			{Name: "book_id", Type: core.BigIntType, NotNull: true},
+			{Name: "key", Type: core.TextType, NotNull: true},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	tmpl, err := template.Load(template.Options{Dialect: core.MySQL{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tmpl.ApplySchema(&buf, &template.SchemaParams{Schema: schema}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = tmpl.ApplyCrud(&buf, &template.CrudParams{
+		GoName:     "Book",
+		PrimaryKey: []string{"id"},
+		Table:      schema.Tables[0],
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tmpl.ApplyChild(&buf, &template.ChildParams{
+		GoName:    "BookLabels",
+		ParentKey: []string{"book_id"},
+		Key:       "key",
+		Table:     labels,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"CREATE TABLE Size (\n    name VARCHAR(8) NOT NULL,\n    number SMALLINT NOT NULL,",
+		// MySQL also escapes backslashes in literals
+		"INSERT INTO Size (name, number) VALUES\n  ('SIZE_BIG', 1),\n  ('SIZE\\\\XL', 2);\n",
+		"CREATE TABLE Book (\n    id INT AUTO_INCREMENT NOT NULL,\n    title LONGTEXT NOT NULL,\n" +
+			"    tags JSON,\n    kind ENUM('KIND_BIG'),\n",
+		"-- name: GetBook :one\nSELECT * FROM Book\nWHERE id = ? LIMIT 1;",
+		"-- name: CreateBook :execresult\nINSERT INTO Book (\n  id, title, tags, kind\n) VALUES (\n" +
+			"  ?, ?, ?, ?\n);",
+		"-- name: UpdateBook :exec\nUPDATE Book SET\n  title = ?,\n  tags = ?,\n  kind = ?\n" +
+			"WHERE id = ?;",
+		"ON DUPLICATE KEY UPDATE `key` = `key`;",
+		"WHERE book_id = ? AND `key` = ?;",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, buf.String())
+		}
+	}
+
+	for _, unwanted := range []string{"EXTENSION", "CREATE TYPE", "RETURNING"} {
+		if strings.Contains(buf.String(), unwanted) {
+			t.Errorf("output contains %q:\n%s", unwanted, buf.String())
+		}
+	}
+}
+
+func TestApplySchemaTemplateForeignKeys(t *testing.T) {
+	t.Parallel()
+
+	schema := core.Schema{
+		Tables: []core.Table{
+			{
+				Name:    "keepers",
+				Columns: []core.Column{{Name: "zoo_id", Type: core.BigIntType}},
+			},
+		},
+		ForeignKeys: []core.ForeignKey{
+			{
+				Table: "keepers",
+				Constraint: core.Constraint{
+					Name:    "keepers_zoo_id_fkey",
+					Type:    core.ForeignKeyConstraint,
+					Columns: []string{"zoo_id"},
+					References: &core.Reference{
+						Table:    "zoos",
+						Columns:  []string{"id"},
+						OnDelete: core.ForeignKeyActionCascade,
+					},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+
+	tmpl := template.New()
+
+	err := tmpl.ApplySchema(&buf, &template.SchemaParams{Schema: schema})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "CREATE TABLE keepers (\n    zoo_id BIGINT\n);\n\n" +
+		"ALTER TABLE keepers ADD CONSTRAINT keepers_zoo_id_fkey FOREIGN KEY(zoo_id) " +
+		"REFERENCES zoos(id) ON DELETE CASCADE;\n"
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("output does not end with %q:\n%s", want, buf.String())
+	}
+}
+
 func TestHeaderTemplate(t *testing.T) {
 	t.Parallel()
 
@@ -117,4 +476,77 @@ func TestHeaderTemplate(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+
+	want := "-- Code generated by protoc-gen-sqlc. DO NOT EDIT.\n" +
+		"-- source:\n--   source1.proto\n--   source2.proto\n"
+	if !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("output does not start with %q:\n%s", want, buf.String())
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	for name, text := range map[string]string{
+		"header.tmpl": "-- {{ .Sources | join \", \" | upper }}\n",
+		"extra.tmpl": `{{ define "crud_extra" }}
+-- name: Count{{ .GoName }} :one
+SELECT count(*) FROM {{ quoteIdent .Name }};
+{{ end }}`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tmpl, err := template.Load(template.Options{Templates: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	err = tmpl.ApplyHeader(&buf, &template.HeaderParams{Sources: []string{"a.proto"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := core.Table{Name: "Book", Columns: []core.Column{{Name: "id", Type: core.BigIntType}}}
+
+	err = tmpl.ApplyCrud(&buf, &template.CrudParams{GoName: "Book", PrimaryKey: []string{"id"}, Table: table})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"-- A.PROTO\n\n-- name: GetBook :one\n",
+		"WHERE id = $1;\n\n-- name: CountBook :one\nSELECT count(*) FROM \"Book\";\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestLoadOverridesErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "crud.tmpl")
+
+	if err := os.WriteFile(path, []byte("-- name: Get :one\n{{ end }}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := template.Load(template.Options{Templates: dir})
+	if err == nil || !strings.Contains(err.Error(), path+":2:") {
+		t.Errorf("Load error = %v, want an error at %s:2", err, path)
+	}
+
+	_, err = template.Load(template.Options{Templates: filepath.Join(dir, "missing")})
+	if err == nil {
+		t.Error("Load of a missing directory succeeded")
+	}
 }